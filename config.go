@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"go.abhg.dev/stitchmd/stitchmd"
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is a project configuration file
+// describing one or more jobs to run with a single invocation.
+//
+//	jobs:
+//	  - input: doc/README.md
+//	    output: README.md
+//	    preface: doc/preface.txt
+//	  - input: doc/contributing/SUMMARY.md
+//	    output: CONTRIBUTING.md
+//	    offset: 1
+type configFile struct {
+	Jobs []*configJob `yaml:"jobs"`
+}
+
+// configJob is a single summary-to-output job in a configuration file.
+// Fields correspond to the command line flags with the same names.
+//
+// Paths are relative to the directory of the configuration file.
+type configJob struct {
+	Input    string             `yaml:"input"` // required
+	Output   string             `yaml:"output"`
+	DepFile  string             `yaml:"depfile"`
+	Assets   string             `yaml:"assets"`
+	Preface  string             `yaml:"preface"`
+	Dir      string             `yaml:"dir"`
+	Offset   int                `yaml:"offset"`
+	NoTOC    bool               `yaml:"no-toc"`
+	TOCDepth int                `yaml:"toc-depth"`
+	TOCStyle stitchmd.TOCStyle  `yaml:"toc-style"`
+	Number   bool               `yaml:"number"`
+	Nav      bool               `yaml:"nav"`
+	Format   stitchmd.Format    `yaml:"format"`
+	Slug     stitchmd.SlugStyle `yaml:"slug"`
+	Profile  string             `yaml:"profile"`
+	Vars     varMap             `yaml:"vars"`
+	VarFile  string             `yaml:"var-file"`
+	Unsafe   bool               `yaml:"unsafe"`
+
+	// Keys present in the job in the configuration file.
+	keys map[string]struct{}
+}
+
+// loadConfig reads and validates the configuration file at the given path.
+func loadConfig(path string) (*configFile, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(src))
+	dec.KnownFields(true)
+
+	var cfg configFile
+	if err := dec.Decode(&cfg); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%v: no jobs found", path)
+		}
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	if len(cfg.Jobs) == 0 {
+		return nil, fmt.Errorf("%v: no jobs found", path)
+	}
+
+	// Fields set to their zero values must still be reported as set,
+	// so record the keys present in each job separately.
+	var rawCfg struct {
+		Jobs []map[string]yaml.Node `yaml:"jobs"`
+	}
+	if err := yaml.Unmarshal(src, &rawCfg); err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	for i, job := range cfg.Jobs {
+		if job == nil || i >= len(rawCfg.Jobs) {
+			continue
+		}
+		job.keys = make(map[string]struct{}, len(rawCfg.Jobs[i]))
+		for key := range rawCfg.Jobs[i] {
+			job.keys[key] = struct{}{}
+		}
+	}
+	for i, job := range cfg.Jobs {
+		if job == nil || job.Input == "" {
+			return nil, fmt.Errorf("%v: job %d: input is required", path, i+1)
+		}
+		// Stdin can't be shared between jobs.
+		if job.Input == "-" {
+			return nil, fmt.Errorf("%v: job %d: input cannot be stdin", path, i+1)
+		}
+		for name := range job.Vars {
+			if !_varName.MatchString(name) {
+				return nil, fmt.Errorf("%v: job %d: invalid variable name %q", path, i+1, name)
+			}
+		}
+		if job.TOCDepth < 0 {
+			return nil, fmt.Errorf("%v: job %d: toc-depth must not be negative", path, i+1)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// params builds the parameters to run this job with.
+// Relative paths are resolved against dir.
+func (j *configJob) params(dir string) *params {
+	resolve := func(p string) string {
+		return resolvePath(dir, p)
+	}
+
+	opts := params{
+		Input:    resolve(j.Input),
+		Output:   resolve(j.Output),
+		DepFile:  resolve(j.DepFile),
+		Assets:   resolve(j.Assets),
+		Preface:  resolve(j.Preface),
+		Dir:      resolve(j.Dir),
+		Offset:   j.Offset,
+		NoTOC:    j.NoTOC,
+		TOCDepth: j.TOCDepth,
+		TOCStyle: j.TOCStyle,
+		Number:   j.Number,
+		Nav:      j.Nav,
+		Format:   j.Format,
+		Slug:     j.Slug,
+		Profile:  j.Profile,
+		Vars:     maps.Clone(j.Vars),
+		VarFile:  resolve(j.VarFile),
+		Unsafe:   j.Unsafe,
+	}
+	if opts.Output == "-" {
+		opts.Output = ""
+	}
+	return &opts
+}
+
+// resolvePath resolves a /-separated path relative to dir.
+// Empty paths, "-", and absolute paths are returned as-is.
+func resolvePath(dir, p string) string {
+	if p == "" || p == "-" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(dir, filepath.FromSlash(p))
+}
+
+// isSet reports whether the field for the given flag
+// was set in the job, even if it was set to its zero value.
+func (j *configJob) isSet(name string) bool {
+	switch name {
+	case "o":
+		name = "output"
+	case "C":
+		name = "dir"
+	}
+	_, ok := j.keys[name]
+	return ok
+}
+
+// name returns a human-readable name for the job
+// to use in messages.
+func (j *configJob) name() string {
+	if j.Output != "" && j.Output != "-" {
+		return j.Output
+	}
+	return j.Input
+}
+
+// runConfig runs all jobs in the configuration file
+// specified in the given parameters.
+//
+// Failures in a job are reported and do not prevent other jobs from
+// running.
+func (cmd *mainCmd) runConfig(opts *params) error {
+	cfg, err := loadConfig(opts.Config)
+	if err != nil {
+		return err
+	}
+
+	log := log.New(cmd.Stderr, "", 0)
+	diag := &diagnosticLogger{
+		W:      cmd.Stderr,
+		Format: opts.Diagnostics,
+		Color:  cmd.shouldColor(opts),
+		Record: cmd.recordDiagnostics,
+	}
+	if diag.Color {
+		diag.W = makeColorable(cmd.Stderr)
+	}
+
+	var (
+		dir    = filepath.Dir(opts.Config)
+		failed int
+		jobs   []*params
+		stale  bool
+	)
+	for i, job := range cfg.Jobs {
+		jobOpts := job.params(dir)
+		jobOpts.Unsafe = jobOpts.Unsafe || opts.Unsafe
+		jobOpts.Diff = opts.Diff
+		jobOpts.Check = opts.Check
+		jobOpts.Watch = opts.Watch
+		jobOpts.Strict = opts.Strict
+		jobOpts.ColorOutput = opts.ColorOutput
+		jobOpts.Diagnostics = opts.Diagnostics
+
+		// Variables set on the command line apply to all jobs,
+		// and take precedence over those set in the job.
+		for name, value := range opts.Vars {
+			if jobOpts.Vars == nil {
+				jobOpts.Vars = make(varMap)
+			}
+			jobOpts.Vars[name] = value
+		}
+
+		// Fields set in the job take precedence over the summary.
+		warnings, err := applySummaryOptions(jobOpts, job.isSet)
+		if diag.Warnings(opts.Strict, stitchmd.CodeFrontMatter, warnings) {
+			err = fmt.Errorf("found %d problem(s) in front matter", len(warnings))
+		}
+		if err != nil {
+			log.Printf("stitchmd: job %d (%v): %v", i+1, job.name(), err)
+			failed++
+			continue
+		}
+
+		if jobOpts.Output == "" && (opts.Diff || opts.Check || opts.Watch) {
+			log.Printf("stitchmd: job %d (%v): output is required with -d, -check, or -watch", i+1, job.name())
+			failed++
+			continue
+		}
+
+		if jobOpts.Output == "" && jobOpts.DepFile != "" {
+			log.Printf("stitchmd: job %d (%v): output is required with depfile", i+1, job.name())
+			failed++
+			continue
+		}
+
+		if jobOpts.Output == "" && jobOpts.Assets != "" {
+			log.Printf("stitchmd: job %d (%v): output is required with assets", i+1, job.name())
+			failed++
+			continue
+		}
+
+		if opts.Watch {
+			jobs = append(jobs, jobOpts)
+			continue
+		}
+
+		if err := cmd.run(jobOpts); err != nil {
+			if errors.Is(err, errStale) {
+				stale = true
+				continue
+			}
+			log.Printf("stitchmd: job %d (%v): %v", i+1, job.name(), err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d jobs failed", failed, len(cfg.Jobs))
+	}
+
+	if opts.Watch {
+		return cmd.watchUntilInterrupted(jobs)
+	}
+
+	if stale {
+		return errStale
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/stitchmd/stitchmd"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	keySet := func(keys ...string) map[string]struct{} {
+		set := make(map[string]struct{}, len(keys))
+		for _, k := range keys {
+			set[k] = struct{}{}
+		}
+		return set
+	}
+
+	tests := []struct {
+		desc    string
+		give    string
+		want    *configFile
+		wantErr string
+	}{
+		{
+			desc: "valid",
+			give: `
+jobs:
+  - input: doc/README.md
+    output: README.md
+    depfile: README.md.d
+    assets: assets
+    preface: doc/preface.txt
+  - input: doc/contrib.md
+    output: CONTRIBUTING.md
+    offset: 1
+    no-toc: true
+    dir: doc
+    format: html
+    slug: pandoc
+    profile: internal
+    vars:
+      Version: 1.2.3
+    var-file: vars.yaml
+    unsafe: true
+`,
+			want: &configFile{
+				Jobs: []*configJob{
+					{
+						Input:   "doc/README.md",
+						Output:  "README.md",
+						DepFile: "README.md.d",
+						Assets:  "assets",
+						Preface: "doc/preface.txt",
+						keys:    keySet("input", "output", "depfile", "assets", "preface"),
+					},
+					{
+						Input:   "doc/contrib.md",
+						Output:  "CONTRIBUTING.md",
+						Offset:  1,
+						NoTOC:   true,
+						Dir:     "doc",
+						Format:  stitchmd.FormatHTML,
+						Slug:    stitchmd.SlugPandoc,
+						Profile: "internal",
+						Vars:    varMap{"Version": "1.2.3"},
+						VarFile: "vars.yaml",
+						Unsafe:  true,
+						keys: keySet(
+							"input", "output", "offset", "no-toc", "dir", "format",
+							"slug", "profile", "vars", "var-file", "unsafe",
+						),
+					},
+				},
+			},
+		},
+		{
+			desc:    "empty",
+			give:    "",
+			wantErr: "no jobs found",
+		},
+		{
+			desc:    "no jobs",
+			give:    "jobs: []",
+			wantErr: "no jobs found",
+		},
+		{
+			desc: "missing input",
+			give: `
+jobs:
+  - input: foo.md
+  - output: README.md
+`,
+			wantErr: "job 2: input is required",
+		},
+		{
+			desc: "stdin input",
+			give: `
+jobs:
+  - input: foo.md
+  - input: "-"
+`,
+			wantErr: "job 2: input cannot be stdin",
+		},
+		{
+			desc: "negative toc-depth",
+			give: `
+jobs:
+  - input: foo.md
+    toc-depth: -1
+`,
+			wantErr: "job 1: toc-depth must not be negative",
+		},
+		{
+			desc: "unknown field",
+			give: `
+jobs:
+  - input: foo.md
+    outptu: README.md
+`,
+			wantErr: "field outptu not found",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "stitchmd.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.give), 0o644))
+
+			got, err := loadConfig(path)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfigJob_params(t *testing.T) {
+	t.Parallel()
+
+	abs, err := filepath.Abs("preface.txt")
+	require.NoError(t, err)
+
+	job := &configJob{
+		Input:   "doc/README.md",
+		Output:  "-",
+		Preface: abs,
+		Dir:     "doc",
+		Offset:  2,
+		NoTOC:   true,
+	}
+	assert.Equal(t, &params{
+		Input:   filepath.Join("root", "doc", "README.md"),
+		Preface: abs,
+		Dir:     filepath.Join("root", "doc"),
+		Offset:  2,
+		NoTOC:   true,
+	}, job.params("root"))
+}
+
+func TestMain_config(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile := func(name, contents string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	}
+	readFile := func(name string) string {
+		bs, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		require.NoError(t, err)
+		return string(bs)
+	}
+
+	writeFile("stitchmd.yaml", `
+jobs:
+  - input: doc/README.md
+    output: README.md
+    preface: doc/preface.txt
+  - input: doc/broken.md
+    output: BROKEN.md
+  - input: contrib/SUMMARY.md
+    output: CONTRIBUTING.md
+    offset: 1
+    no-toc: true
+`)
+	writeFile("doc/README.md", "- [Intro](intro.md)\n")
+	writeFile("doc/intro.md", "# Intro\n\nHello.\n")
+	writeFile("doc/preface.txt", "<!-- generated -->\n")
+	writeFile("doc/broken.md", "- [missing](missing.md)\n")
+	writeFile("contrib/SUMMARY.md", "- [Contributing](contrib.md)\n")
+	writeFile("contrib/contrib.md", "# Contributing\n\nPRs welcome.\n")
+
+	run := func(args ...string) (exitCode int, stdout, stderr string) {
+		var stdoutBuf, stderrBuf bytes.Buffer
+		exitCode = (&mainCmd{
+			Stdin:  bytes.NewReader(nil),
+			Stdout: &stdoutBuf,
+			Stderr: &stderrBuf,
+			Getwd: func() (string, error) {
+				return dir, nil
+			},
+			Getenv: nopGetenv,
+		}).Run(append(args, "-config", filepath.Join(dir, "stitchmd.yaml")))
+		return exitCode, stdoutBuf.String(), stderrBuf.String()
+	}
+
+	// All jobs run even though one of them fails.
+	exitCode, _, stderr := run()
+	assert.Equal(t, _exitError, exitCode)
+	assert.Contains(t, stderr, "job 2 (BROKEN.md): error reading markdown")
+	assert.Contains(t, stderr, "1 of 3 jobs failed")
+	assert.Equal(t,
+		"<!-- generated -->\n- [Intro](#intro)\n\n# Intro\n\nHello.\n",
+		readFile("README.md"))
+	assert.Equal(t,
+		"## Contributing\n\nPRs welcome.\n",
+		readFile("CONTRIBUTING.md"))
+
+	// Fix the broken job and verify -check across all jobs.
+	writeFile("doc/missing.md", "# Missing\n")
+	exitCode, _, stderr = run("-check")
+	assert.Equal(t, _exitStale, exitCode)
+	assert.Equal(t, filepath.Join(dir, "BROKEN.md")+" is out of date\n", stderr)
+
+	exitCode, _, stderr = run()
+	assert.Equal(t, _exitOK, exitCode, "stderr: %s", stderr)
+	exitCode, stdout, stderr := run("-check", "-d")
+	assert.Equal(t, _exitOK, exitCode)
+	assert.Empty(t, stdout)
+	assert.Empty(t, stderr)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// writeDepfile writes a Makefile-style dependency file to w
+// declaring that target depends on the given files.
+//
+//	README.md: \
+//	  doc/SUMMARY.md \
+//	  doc/intro.md
+//
+// The output is compatible with both Make and Ninja.
+func writeDepfile(w io.Writer, target string, deps []string) error {
+	bw := bufio.NewWriter(w)
+	_, _ = bw.WriteString(escapeDepfilePath(target))
+	_, _ = bw.WriteString(":")
+	for _, dep := range deps {
+		_, _ = bw.WriteString(" \\\n  ")
+		_, _ = bw.WriteString(escapeDepfilePath(dep))
+	}
+	_, _ = bw.WriteString("\n")
+	return bw.Flush()
+}
+
+var _depfileEscaper = strings.NewReplacer(
+	" ", `\ `,
+	"#", `\#`,
+	"$", "$$",
+)
+
+// escapeDepfilePath escapes characters in a path
+// that have special meaning in Makefile rules.
+func escapeDepfilePath(p string) string {
+	return _depfileEscaper.Replace(p)
+}
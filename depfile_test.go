@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteDepfile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc   string
+		target string
+		deps   []string
+		want   string
+	}{
+		{
+			desc:   "no deps",
+			target: "README.md",
+			want:   "README.md:\n",
+		},
+		{
+			desc:   "deps",
+			target: "README.md",
+			deps:   []string{"doc/SUMMARY.md", "doc/intro.md"},
+			want: "README.md: \\\n" +
+				"  doc/SUMMARY.md \\\n" +
+				"  doc/intro.md\n",
+		},
+		{
+			desc:   "escaping",
+			target: "out dir/README.md",
+			deps:   []string{"doc/my file.md", "doc/$cost.md", "doc/#1.md"},
+			want: "out\\ dir/README.md: \\\n" +
+				"  doc/my\\ file.md \\\n" +
+				"  doc/$$cost.md \\\n" +
+				"  doc/\\#1.md\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			require.NoError(t, writeDepfile(&buf, tt.target, tt.deps))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
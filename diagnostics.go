@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.abhg.dev/stitchmd/stitchmd"
+)
+
+// diagnosticsFormat is the format in which problems
+// with the inputs are reported.
+type diagnosticsFormat int
+
+const (
+	// Plain text messages in the form "file:line:col:message".
+	diagnosticsText diagnosticsFormat = iota
+
+	// One JSON object per problem, one per line.
+	diagnosticsJSON
+)
+
+var _ flag.Getter = (*diagnosticsFormat)(nil)
+
+func (f diagnosticsFormat) String() string {
+	switch f {
+	case diagnosticsText:
+		return "text"
+	case diagnosticsJSON:
+		return "json"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(f))
+	}
+}
+
+func (f diagnosticsFormat) Get() interface{} {
+	return f
+}
+
+func (f *diagnosticsFormat) Set(s string) error {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "text":
+		*f = diagnosticsText
+	case "json":
+		*f = diagnosticsJSON
+	default:
+		return errors.New("must be one of 'text', 'json'")
+	}
+	return nil
+}
+
+// Severities of diagnostics.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// diagnostic is a single problem reported with -diagnostics json.
+type diagnostic struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Code     string `json:"code,omitempty"`
+	Message  string `json:"message"`
+	Hint     string `json:"hint,omitempty"`
+
+	// Text of the line at the position, if known.
+	source string
+
+	// Positions of the summary items
+	// through which the file with the problem was included.
+	via []stitchmd.Position
+}
+
+// diagnose builds diagnostics for the given error.
+//
+// Errors at known positions are reported at those positions.
+// If such an error holds other positioned errors,
+// for example, problems in a file included by a summary item,
+// those are reported at their own positions instead.
+func diagnose(d diagnostic, err error) []diagnostic {
+	posErr, ok := err.(*stitchmd.Error)
+	if !ok {
+		d.Message, d.Hint = splitHint(err)
+		return []diagnostic{d}
+	}
+
+	if inner := positionedErrors(posErr.Err); len(inner) > 0 {
+		d.via = append(d.via[:len(d.via):len(d.via)], posErr.Pos)
+		var ds []diagnostic
+		for _, err := range inner {
+			ds = append(ds, diagnose(d, err)...)
+		}
+		return ds
+	}
+
+	d.File = posErr.Pos.File
+	d.Line = posErr.Pos.Line
+	d.Column = posErr.Pos.Column
+	d.Message, d.Hint = splitHint(posErr.Err)
+	d.source = posErr.Source
+	return []diagnostic{d}
+}
+
+// splitHint returns the message of the given error
+// separately from the suggestion for fixing it, if any.
+func splitHint(err error) (msg, hint string) {
+	msg = err.Error()
+	if hint = stitchmd.Hint(err); hint != "" {
+		msg = strings.TrimSuffix(msg, "; "+hint)
+	}
+	return msg, hint
+}
+
+// positionedErrors returns the outermost positioned errors in err.
+func positionedErrors(err error) []error {
+	switch err := err.(type) {
+	case *stitchmd.Error:
+		return []error{err}
+	case interface{ Unwrap() []error }:
+		var errs []error
+		for _, err := range err.Unwrap() {
+			errs = append(errs, positionedErrors(err)...)
+		}
+		return errs
+	case interface{ Unwrap() error }:
+		return positionedErrors(err.Unwrap())
+	default:
+		return nil
+	}
+}
+
+// diagnosticLogger reports problems with the inputs
+// in the format requested with -diagnostics.
+type diagnosticLogger struct {
+	W      io.Writer // required
+	Format diagnosticsFormat
+	Color  bool
+
+	// Dir is the directory that file paths in problems are relative to.
+	// Reported paths are joined with it
+	// so that they're relative to the current directory.
+	Dir string
+
+	// Record, if set, is called with each reported problem.
+	Record func(diagnostic)
+}
+
+// Warnings reports the given warnings.
+//
+// With strict, they're reported as errors instead,
+// and Warnings reports true if there were any.
+func (l *diagnosticLogger) Warnings(strict bool, code string, warnings []error) (failed bool) {
+	severity := severityWarning
+	if strict {
+		severity = severityError
+	}
+	l.report(severity, code, warnings)
+	return strict && len(warnings) > 0
+}
+
+// Errors reports the given errors.
+// code may be empty if the kind of the errors isn't known.
+func (l *diagnosticLogger) Errors(code string, errs []error) {
+	l.report(severityError, code, errs)
+}
+
+func (l *diagnosticLogger) report(severity, code string, errs []error) {
+	enc := json.NewEncoder(l.W)
+	for _, err := range errs {
+		for _, d := range diagnose(diagnostic{Severity: severity, Code: code}, err) {
+			if d.File != "" && l.Dir != "" {
+				d.File = filepath.Join(l.Dir, filepath.FromSlash(d.File))
+			}
+			if l.Record != nil {
+				l.Record(d)
+			}
+
+			if l.Format == diagnosticsJSON {
+				// Failures to write to stderr can't be reported anywhere.
+				_ = enc.Encode(d)
+			} else {
+				l.render(d)
+			}
+		}
+	}
+}
+
+// ANSI escape sequences used to color diagnostics.
+const (
+	_ansiReset  = "\x1b[0m"
+	_ansiBold   = "\x1b[1m"
+	_ansiRed    = "\x1b[31m"
+	_ansiYellow = "\x1b[33m"
+	_ansiBlue   = "\x1b[34m"
+	_ansiCyan   = "\x1b[36m"
+)
+
+// render writes a diagnostic as text in the style of a compiler:
+//
+//	foo.md:3:6:broken link "bar.md": file not found
+//	  3 | See [bar](bar.md).
+//	    |      ^
+//	  hint: ...
+//
+// The source excerpt is left out if the line isn't known,
+// and the hint is left out if there isn't one.
+func (l *diagnosticLogger) render(d diagnostic) {
+	color := func(code, s string) string {
+		if !l.Color {
+			return s
+		}
+		return code + s + _ansiReset
+	}
+
+	severityColor := _ansiRed
+	if d.Severity == severityWarning {
+		severityColor = _ansiYellow
+	}
+
+	var sb strings.Builder
+	if d.Severity == severityWarning {
+		sb.WriteString(color(_ansiBold+_ansiYellow, "warning:") + " ")
+	}
+
+	var header strings.Builder
+	for _, pos := range d.via {
+		header.WriteString(pos.String())
+		header.WriteString(":")
+	}
+	if d.Line > 0 {
+		pos := stitchmd.Position{File: d.File, Line: d.Line, Column: d.Column}
+		header.WriteString(pos.String())
+		header.WriteString(":")
+	}
+	header.WriteString(d.Message)
+	sb.WriteString(color(_ansiBold, header.String()))
+	sb.WriteString("\n")
+
+	if d.Line > 0 && d.source != "" {
+		lineNo := strconv.Itoa(d.Line)
+		gutter := strings.Repeat(" ", len(lineNo))
+		fmt.Fprintf(&sb, "  %v %v\n", color(_ansiBlue, lineNo+" |"), d.source)
+		fmt.Fprintf(&sb, "  %v %v%v\n",
+			color(_ansiBlue, gutter+" |"),
+			caretPadding(d.source, d.Column),
+			color(_ansiBold+severityColor, "^"))
+	}
+
+	if d.Hint != "" {
+		fmt.Fprintf(&sb, "  %v %v\n", color(_ansiBold+_ansiCyan, "hint:"), d.Hint)
+	}
+
+	// Failures to write to stderr can't be reported anywhere.
+	_, _ = io.WriteString(l.W, sb.String())
+}
+
+// caretPadding returns the whitespace that places a caret
+// under the given 1-indexed byte column of a line.
+//
+// Tabs in the line are kept so that the caret lines up with the text.
+func caretPadding(line string, column int) string {
+	if column < 1 {
+		return ""
+	}
+	if column-1 < len(line) {
+		line = line[:column-1]
+	}
+
+	var sb strings.Builder
+	for _, r := range line {
+		if r == '\t' {
+			sb.WriteRune('\t')
+		} else {
+			sb.WriteRune(' ')
+		}
+	}
+	return sb.String()
+}
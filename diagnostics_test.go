@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/stitchmd/stitchmd"
+)
+
+func TestDiagnosticsFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		give string
+		want diagnosticsFormat
+	}{
+		{give: "text", want: diagnosticsText},
+		{give: "json", want: diagnosticsJSON},
+		{give: " JSON ", want: diagnosticsJSON},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.give, func(t *testing.T) {
+			t.Parallel()
+
+			var got diagnosticsFormat
+			require.NoError(t, got.Set(tt.give))
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want, got.Get())
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		t.Parallel()
+
+		var got diagnosticsFormat
+		assert.ErrorContains(t, got.Set("xml"), "must be one of 'text', 'json'")
+		assert.Equal(t, "unknown (42)", diagnosticsFormat(42).String())
+	})
+}
+
+func TestDiagnose(t *testing.T) {
+	t.Parallel()
+
+	summaryPos := stitchmd.Position{File: "summary.md", Line: 3, Column: 3}
+	fooPos := stitchmd.Position{File: "foo.md", Line: 5, Column: 8}
+	barPos := stitchmd.Position{File: "foo.md", Line: 7, Column: 1}
+
+	tests := []struct {
+		desc string
+		give error
+		want []diagnostic
+	}{
+		{
+			desc: "no position",
+			give: errors.New("great sadness"),
+			want: []diagnostic{
+				{Severity: "error", Message: "great sadness"},
+			},
+		},
+		{
+			desc: "position",
+			give: &stitchmd.Error{Pos: fooPos, Err: errors.New("great sadness")},
+			want: []diagnostic{
+				{
+					File:     "foo.md",
+					Line:     5,
+					Column:   8,
+					Severity: "error",
+					Message:  "great sadness",
+				},
+			},
+		},
+		{
+			desc: "source",
+			give: &stitchmd.Error{
+				Pos:    summaryPos,
+				Err:    errors.New("great sadness"),
+				Source: "- [Foo](../foo.md)",
+			},
+			want: []diagnostic{
+				{
+					File:     "summary.md",
+					Line:     3,
+					Column:   3,
+					Severity: "error",
+					Message:  "great sadness",
+					source:   "- [Foo](../foo.md)",
+				},
+			},
+		},
+		{
+			desc: "nested positions",
+			give: &stitchmd.Error{
+				Pos: summaryPos,
+				Err: fmt.Errorf("%w", errors.Join(
+					&stitchmd.Error{Pos: fooPos, Err: errors.New("foo")},
+					&stitchmd.Error{Pos: barPos, Err: errors.New("bar")},
+				)),
+			},
+			want: []diagnostic{
+				{
+					File:     "foo.md",
+					Line:     5,
+					Column:   8,
+					Severity: "error",
+					Message:  "foo",
+					via:      []stitchmd.Position{summaryPos},
+				},
+				{
+					File:     "foo.md",
+					Line:     7,
+					Column:   1,
+					Severity: "error",
+					Message:  "bar",
+					via:      []stitchmd.Position{summaryPos},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got := diagnose(diagnostic{Severity: "error"}, tt.give)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDiagnosticLogger(t *testing.T) {
+	t.Parallel()
+
+	warnings := []error{
+		&stitchmd.Error{
+			Pos: stitchmd.Position{File: "foo.md", Line: 3, Column: 6},
+			Err: errors.New(`broken link "bar.md": file not found`),
+		},
+	}
+
+	tests := []struct {
+		desc   string
+		format diagnosticsFormat
+		strict bool
+		want   string
+	}{
+		{
+			desc:   "text",
+			format: diagnosticsText,
+			want:   `warning: foo.md:3:6:broken link "bar.md": file not found` + "\n",
+		},
+		{
+			desc:   "text/strict",
+			format: diagnosticsText,
+			strict: true,
+			want:   `foo.md:3:6:broken link "bar.md": file not found` + "\n",
+		},
+		{
+			desc:   "json",
+			format: diagnosticsJSON,
+			want: `{"file":"foo.md","line":3,"column":6,"severity":"warning",` +
+				`"code":"broken-link","message":"broken link \"bar.md\": file not found"}` + "\n",
+		},
+		{
+			desc:   "json/strict",
+			format: diagnosticsJSON,
+			strict: true,
+			want: `{"file":"foo.md","line":3,"column":6,"severity":"error",` +
+				`"code":"broken-link","message":"broken link \"bar.md\": file not found"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			failed := (&diagnosticLogger{W: &buf, Format: tt.format}).
+				Warnings(tt.strict, stitchmd.CodeBrokenLink, warnings)
+			assert.Equal(t, tt.strict, failed)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestDiagnosticLogger_render(t *testing.T) {
+	t.Parallel()
+
+	// Hints can only be built by the stitchmd package,
+	// so get one from a real failure.
+	_, hintErr := stitchmd.Run(context.Background(), stitchmd.Options{
+		FS:          os.DirFS(t.TempDir()),
+		Summary:     []byte("- [Foo](../foo.md)\n"),
+		SummaryPath: "summary.md",
+	})
+	var inputErr *stitchmd.InputError
+	require.ErrorAs(t, hintErr, &inputErr)
+
+	tests := []struct {
+		desc     string
+		severity string
+		give     error
+		color    bool
+		want     string
+	}{
+		{
+			desc:     "no position",
+			severity: "error",
+			give:     errors.New("great sadness"),
+			want:     "great sadness\n",
+		},
+		{
+			desc:     "no source",
+			severity: "warning",
+			give: &stitchmd.Error{
+				Pos: stitchmd.Position{File: "foo.md", Line: 3, Column: 6},
+				Err: errors.New("great sadness"),
+			},
+			want: "warning: foo.md:3:6:great sadness\n",
+		},
+		{
+			desc:     "source",
+			severity: "error",
+			give: &stitchmd.Error{
+				Pos:    stitchmd.Position{File: "foo.md", Line: 12, Column: 6},
+				Err:    errors.New("great sadness"),
+				Source: "See [bar](bar.md).",
+			},
+			want: "foo.md:12:6:great sadness\n" +
+				"  12 | See [bar](bar.md).\n" +
+				"     |      ^\n",
+		},
+		{
+			desc:     "tabs",
+			severity: "error",
+			give: &stitchmd.Error{
+				Pos:    stitchmd.Position{File: "foo.md", Line: 1, Column: 4},
+				Err:    errors.New("great sadness"),
+				Source: "\t- [bar](bar.md)",
+			},
+			want: "foo.md:1:4:great sadness\n" +
+				"  1 | \t- [bar](bar.md)\n" +
+				"    | \t  ^\n",
+		},
+		{
+			desc:     "nested",
+			severity: "error",
+			give: &stitchmd.Error{
+				Pos: stitchmd.Position{File: "summary.md", Line: 1, Column: 3},
+				Err: fmt.Errorf("%w", &stitchmd.Error{
+					Pos:    stitchmd.Position{File: "foo.md", Line: 2, Column: 1},
+					Err:    errors.New("great sadness"),
+					Source: "{{ .Foo }}",
+				}),
+			},
+			want: "summary.md:1:3:foo.md:2:1:great sadness\n" +
+				"  2 | {{ .Foo }}\n" +
+				"    | ^\n",
+		},
+		{
+			desc:     "hint",
+			severity: "error",
+			give:     inputErr.Errs[0],
+			want: `summary.md:1:3:invalid path "../foo.md"` + "\n" +
+				"  1 | - [Foo](../foo.md)\n" +
+				"    |   ^\n" +
+				"  hint: did you mean to use -unsafe?\n",
+		},
+		{
+			desc:     "color",
+			severity: "warning",
+			color:    true,
+			give: &stitchmd.Error{
+				Pos:    stitchmd.Position{File: "foo.md", Line: 3, Column: 2},
+				Err:    errors.New("great sadness"),
+				Source: "foo",
+			},
+			want: "\x1b[1m\x1b[33mwarning:\x1b[0m \x1b[1mfoo.md:3:2:great sadness\x1b[0m\n" +
+				"  \x1b[34m3 |\x1b[0m foo\n" +
+				"  \x1b[34m  |\x1b[0m  \x1b[1m\x1b[33m^\x1b[0m\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			(&diagnosticLogger{W: &buf, Color: tt.color}).
+				report(tt.severity, "", []error{tt.give})
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
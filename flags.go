@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"strings"
+
+	"go.abhg.dev/stitchmd/stitchmd"
 )
 
 var (
@@ -18,16 +20,37 @@ var (
 
 // params defines the parameters for the command line program.
 type params struct {
-	Preface string
-	Input   string // defaults to stdin
-	Output  string // defaults to stdout
-	Dir     string
-	Offset  int
-	NoTOC   bool
-	Unsafe  bool
+	Preface  string
+	Config   string
+	Input    string // defaults to stdin
+	Output   string // defaults to stdout
+	DepFile  string
+	Assets   string
+	Dir      string
+	Offset   int
+	NoTOC    bool
+	TOCDepth int
+	TOCStyle stitchmd.TOCStyle
+	Number   bool
+	Nav      bool
+	Unsafe   bool
+	Format   stitchmd.Format
+	Slug     stitchmd.SlugStyle
+	Profile  string
+	Vars     varMap
+	VarFile  string
+
+	// Variables from the front matter of the summary.
+	// VarFile and Vars take precedence over these.
+	SummaryVars varMap
 
 	Diff        bool
+	Check       bool
+	Watch       bool
+	Strict      bool
 	ColorOutput colorOutput
+	Diagnostics diagnosticsFormat
+	SARIF       string
 }
 
 // cliParser parses command line arguments.
@@ -35,6 +58,10 @@ type cliParser struct {
 	Stdout io.Writer // required
 	Stderr io.Writer // required
 
+	// Record, if set, is called with each problem
+	// found in the front matter of the summary.
+	Record func(diagnostic)
+
 	version bool
 	help    bool
 }
@@ -48,14 +75,33 @@ func (p *cliParser) newFlagSet() (*params, *flag.FlagSet) {
 
 	var opts params
 	flag.StringVar(&opts.Preface, "preface", "", "")
+	flag.StringVar(&opts.Config, "config", "", "")
 	flag.StringVar(&opts.Output, "o", "", "")
+	flag.StringVar(&opts.DepFile, "M", "", "")
+	flag.StringVar(&opts.DepFile, "depfile", "", "")
+	flag.StringVar(&opts.Assets, "assets", "", "")
 	flag.StringVar(&opts.Dir, "C", "", "")
 	flag.IntVar(&opts.Offset, "offset", 0, "")
 	flag.BoolVar(&opts.NoTOC, "no-toc", false, "")
+	flag.IntVar(&opts.TOCDepth, "toc-depth", 0, "")
+	flag.Var(&opts.TOCStyle, "toc-style", "")
+	flag.BoolVar(&opts.Number, "number", false, "")
+	flag.BoolVar(&opts.Nav, "nav", false, "")
+	flag.Var(&opts.Format, "format", "")
+	flag.Var(&opts.Slug, "slug", "")
+	flag.StringVar(&opts.Profile, "profile", "", "")
+	flag.Var(&opts.Vars, "var", "")
+	flag.StringVar(&opts.VarFile, "var-file", "", "")
 	flag.Var(&opts.ColorOutput, "color", "")
+	flag.Var(&opts.Diagnostics, "diagnostics", "")
+	flag.StringVar(&opts.SARIF, "sarif", "", "")
 	flag.BoolVar(&opts.Diff, "d", false, "")
 	flag.BoolVar(&opts.Diff, "diff", false, "")
+	flag.BoolVar(&opts.Check, "check", false, "")
+	flag.BoolVar(&opts.Watch, "w", false, "")
+	flag.BoolVar(&opts.Watch, "watch", false, "")
 	flag.BoolVar(&opts.Unsafe, "unsafe", false, "")
+	flag.BoolVar(&opts.Strict, "strict", false, "")
 
 	flag.BoolVar(&p.version, "version", false, "")
 	flag.BoolVar(&p.help, "help", false, "")
@@ -75,6 +121,10 @@ const (
 // Parses and returns command line parameters.
 // This function does not return an error to ensure
 // that error messages are not double-printed.
+//
+// If the summary's front matter has problems,
+// the parameters are returned with cliParseError
+// so that the problems can still be written to the -sarif report.
 func (p *cliParser) Parse(args []string) (*params, cliParseResult) {
 	opts, fset := p.newFlagSet()
 	if err := fset.Parse(args); err != nil {
@@ -96,6 +146,35 @@ func (p *cliParser) Parse(args []string) (*params, cliParseResult) {
 		return nil, cliParseHelp
 	}
 
+	if opts.TOCDepth < 0 {
+		fmt.Fprintln(p.Stderr, "-toc-depth must not be negative")
+		fset.Usage()
+		return nil, cliParseError
+	}
+
+	// These conflict with -watch with or without -config.
+	if opts.Watch {
+		var errmsg string
+		switch {
+		case opts.Diff:
+			errmsg = "cannot use -watch with -d"
+		case opts.Check:
+			errmsg = "cannot use -watch with -check"
+		case opts.SARIF != "":
+			// The report is written when stitchmd exits.
+			errmsg = "cannot use -sarif with -watch"
+		}
+		if errmsg != "" {
+			fmt.Fprintln(p.Stderr, errmsg)
+			fset.Usage()
+			return nil, cliParseError
+		}
+	}
+
+	if len(opts.Config) > 0 {
+		return p.parseConfigMode(fset, opts, args)
+	}
+
 	switch len(args) {
 	case 0:
 		fmt.Fprintln(p.Stderr, "please specify a file name")
@@ -117,6 +196,37 @@ func (p *cliParser) Parse(args []string) (*params, cliParseResult) {
 		opts.Output = ""
 	}
 
+	// Flags set on the command line take precedence over the summary.
+	setFlags := make(map[string]struct{})
+	fset.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = struct{}{}
+	})
+	warnings, err := applySummaryOptions(opts, func(name string) bool {
+		_, ok := setFlags[name]
+		if name == "depfile" {
+			_, isSetShort := setFlags["M"]
+			ok = ok || isSetShort
+		}
+		return ok
+	})
+	if err != nil {
+		fmt.Fprintln(p.Stderr, err)
+		return nil, cliParseError
+	}
+	diag := &diagnosticLogger{
+		W:      p.Stderr,
+		Format: opts.Diagnostics,
+		Record: p.Record,
+	}
+	if diag.Warnings(opts.Strict, stitchmd.CodeFrontMatter, warnings) {
+		// The count has no location, so it's left out of the -sarif report.
+		diag.Record = nil
+		diag.Errors("", []error{
+			fmt.Errorf("found %d problem(s) in front matter", len(warnings)),
+		})
+		return opts, cliParseError
+	}
+
 	// Reject -d if -o is not set.
 	if opts.Diff && opts.Output == "" {
 		fmt.Fprintln(p.Stderr, "cannot use -d without -o")
@@ -124,6 +234,88 @@ func (p *cliParser) Parse(args []string) (*params, cliParseResult) {
 		return nil, cliParseError
 	}
 
+	// Reject -depfile if -o is not set.
+	// The output file is the target of the rule.
+	if opts.DepFile != "" && opts.Output == "" {
+		fmt.Fprintln(p.Stderr, "cannot use -depfile without -o")
+		fset.Usage()
+		return nil, cliParseError
+	}
+
+	// Reject -assets if -o is not set.
+	// Links to assets are relative to the output file.
+	if opts.Assets != "" && opts.Output == "" {
+		fmt.Fprintln(p.Stderr, "cannot use -assets without -o")
+		fset.Usage()
+		return nil, cliParseError
+	}
+
+	// Reject -check if -o is not set.
+	if opts.Check && opts.Output == "" {
+		fmt.Fprintln(p.Stderr, "cannot use -check without -o")
+		fset.Usage()
+		return nil, cliParseError
+	}
+
+	if opts.Watch {
+		var errmsg string
+		switch {
+		case opts.Input == "":
+			errmsg = "cannot use -watch with stdin"
+		case opts.Output == "":
+			errmsg = "cannot use -watch without -o"
+		}
+		if errmsg != "" {
+			fmt.Fprintln(p.Stderr, errmsg)
+			fset.Usage()
+			return nil, cliParseError
+		}
+	}
+
+	return opts, cliParseSuccess
+}
+
+// _jobFlags lists flags that configure a single job.
+// These must be specified in the configuration file with -config.
+var _jobFlags = map[string]struct{}{
+	"preface":   {},
+	"o":         {},
+	"M":         {},
+	"depfile":   {},
+	"assets":    {},
+	"C":         {},
+	"offset":    {},
+	"no-toc":    {},
+	"toc-depth": {},
+	"toc-style": {},
+	"number":    {},
+	"nav":       {},
+	"format":    {},
+	"slug":      {},
+	"profile":   {},
+	"var-file":  {},
+}
+
+// parseConfigMode validates the parameters for -config.
+func (p *cliParser) parseConfigMode(fset *flag.FlagSet, opts *params, args []string) (*params, cliParseResult) {
+	if len(args) > 0 {
+		fmt.Fprintf(p.Stderr, "unexpected arguments with -config: %q\n", args)
+		fset.Usage()
+		return nil, cliParseError
+	}
+
+	var conflicts []string
+	fset.Visit(func(f *flag.Flag) {
+		if _, ok := _jobFlags[f.Name]; ok {
+			conflicts = append(conflicts, "-"+f.Name)
+		}
+	})
+	if len(conflicts) > 0 {
+		fmt.Fprintf(p.Stderr, "cannot use %v with -config\n", strings.Join(conflicts, ", "))
+		fset.Usage()
+		return nil, cliParseError
+	}
+
 	return opts, cliParseSuccess
 }
 
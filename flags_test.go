@@ -9,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.abhg.dev/stitchmd/stitchmd"
 )
 
 // Verifies that all flags registered against the flag set
@@ -104,6 +105,16 @@ func TestCLIParser_Parse(t *testing.T) {
 			args: []string{"-color=false", "bar"},
 			want: params{ColorOutput: colorOutputNever, Input: "bar"},
 		},
+		{
+			desc: "diagnostics",
+			args: []string{"-diagnostics", "json", "bar"},
+			want: params{Diagnostics: diagnosticsJSON, Input: "bar"},
+		},
+		{
+			desc: "sarif",
+			args: []string{"-sarif", "out.sarif", "bar"},
+			want: params{SARIF: "out.sarif", Input: "bar"},
+		},
 		{
 			desc: "diff",
 			args: []string{"-d", "-o", "foo", "bar"},
@@ -122,6 +133,167 @@ func TestCLIParser_Parse(t *testing.T) {
 				Input:  "bar",
 			},
 		},
+		{
+			desc: "check",
+			args: []string{"-check", "-o", "foo", "bar"},
+			want: params{
+				Check:  true,
+				Output: "foo",
+				Input:  "bar",
+			},
+		},
+		{
+			desc: "check and diff",
+			args: []string{"-check", "-d", "-o", "foo", "bar"},
+			want: params{
+				Check:  true,
+				Diff:   true,
+				Output: "foo",
+				Input:  "bar",
+			},
+		},
+		{
+			desc: "watch",
+			args: []string{"-watch", "-o", "foo", "bar"},
+			want: params{
+				Watch:  true,
+				Output: "foo",
+				Input:  "bar",
+			},
+		},
+		{
+			desc: "watch alias",
+			args: []string{"-w", "-o", "foo", "bar"},
+			want: params{
+				Watch:  true,
+				Output: "foo",
+				Input:  "bar",
+			},
+		},
+		{
+			desc: "depfile",
+			args: []string{"-depfile", "foo.d", "-o", "foo", "bar"},
+			want: params{
+				DepFile: "foo.d",
+				Output:  "foo",
+				Input:   "bar",
+			},
+		},
+		{
+			desc: "depfile alias",
+			args: []string{"-M", "foo.d", "-o", "foo", "bar"},
+			want: params{
+				DepFile: "foo.d",
+				Output:  "foo",
+				Input:   "bar",
+			},
+		},
+		{
+			desc: "format",
+			args: []string{"-format", "html", "-o", "foo.html", "bar"},
+			want: params{
+				Format: stitchmd.FormatHTML,
+				Output: "foo.html",
+				Input:  "bar",
+			},
+		},
+		{
+			desc: "slug",
+			args: []string{"-slug", "gitlab", "bar"},
+			want: params{
+				Slug:  stitchmd.SlugGitLab,
+				Input: "bar",
+			},
+		},
+		{
+			desc: "toc depth and style",
+			args: []string{"-toc-depth", "2", "-toc-style", "details", "bar"},
+			want: params{
+				TOCDepth: 2,
+				TOCStyle: stitchmd.TOCDetails,
+				Input:    "bar",
+			},
+		},
+		{
+			desc: "number",
+			args: []string{"-number", "bar"},
+			want: params{
+				Number: true,
+				Input:  "bar",
+			},
+		},
+		{
+			desc: "nav",
+			args: []string{"-nav", "bar"},
+			want: params{
+				Nav:   true,
+				Input: "bar",
+			},
+		},
+		{
+			desc: "profile",
+			args: []string{"-profile", "internal", "bar"},
+			want: params{
+				Profile: "internal",
+				Input:   "bar",
+			},
+		},
+		{
+			desc: "vars",
+			args: []string{"-var", "Version=1.2", "-var", "Name=a=b", "-var-file", "vars.yaml", "bar"},
+			want: params{
+				Vars:    varMap{"Version": "1.2", "Name": "a=b"},
+				VarFile: "vars.yaml",
+				Input:   "bar",
+			},
+		},
+		{
+			desc: "assets",
+			args: []string{"-assets", "out/assets", "-o", "out/README.md", "bar"},
+			want: params{
+				Assets: "out/assets",
+				Output: "out/README.md",
+				Input:  "bar",
+			},
+		},
+		{
+			desc: "strict",
+			args: []string{"-strict", "bar"},
+			want: params{
+				Strict: true,
+				Input:  "bar",
+			},
+		},
+		{
+			desc: "config",
+			args: []string{"-config", "stitchmd.yaml", "-check", "-d", "-unsafe"},
+			want: params{
+				Config: "stitchmd.yaml",
+				Check:  true,
+				Diff:   true,
+				Unsafe: true,
+			},
+		},
+		{
+			desc: "config/watch",
+			args: []string{"-config", "stitchmd.yaml", "-watch"},
+			want: params{
+				Config: "stitchmd.yaml",
+				Watch:  true,
+			},
+		},
+		{
+			desc:    "config/watch/check",
+			args:    []string{"-config", "stitchmd.yaml", "-watch", "-check"},
+			wantRes: cliParseError,
+			wantErr: "cannot use -watch with -check",
+		},
+		{
+			desc:    "config/watch/diff",
+			args:    []string{"-config", "stitchmd.yaml", "-watch", "-d"},
+			wantRes: cliParseError,
+			wantErr: "cannot use -watch with -d",
+		},
 		{
 			desc: "preface",
 			args: []string{"-preface", "foo", "-o", "bar", "baz"},
@@ -137,6 +309,96 @@ func TestCLIParser_Parse(t *testing.T) {
 			wantRes: cliParseError,
 			wantErr: "cannot use -d without -o",
 		},
+		{
+			desc:    "check/missing o",
+			args:    []string{"-check", "bar"},
+			wantRes: cliParseError,
+			wantErr: "cannot use -check without -o",
+		},
+		{
+			desc:    "watch/missing o",
+			args:    []string{"-watch", "bar"},
+			wantRes: cliParseError,
+			wantErr: "cannot use -watch without -o",
+		},
+		{
+			desc:    "watch/stdin",
+			args:    []string{"-watch", "-o", "foo", "-"},
+			wantRes: cliParseError,
+			wantErr: "cannot use -watch with stdin",
+		},
+		{
+			desc:    "watch/diff",
+			args:    []string{"-watch", "-d", "-o", "foo", "bar"},
+			wantRes: cliParseError,
+			wantErr: "cannot use -watch with -d",
+		},
+		{
+			desc:    "watch/check",
+			args:    []string{"-watch", "-check", "-o", "foo", "bar"},
+			wantRes: cliParseError,
+			wantErr: "cannot use -watch with -check",
+		},
+		{
+			desc:    "toc-depth/negative",
+			args:    []string{"-toc-depth", "-1", "bar"},
+			wantRes: cliParseError,
+			wantErr: "-toc-depth must not be negative",
+		},
+		{
+			desc:    "watch/sarif",
+			args:    []string{"-watch", "-sarif", "out.sarif", "-o", "foo", "bar"},
+			wantRes: cliParseError,
+			wantErr: "cannot use -sarif with -watch",
+		},
+		{
+			desc:    "depfile/missing o",
+			args:    []string{"-M", "foo.d", "bar"},
+			wantRes: cliParseError,
+			wantErr: "cannot use -depfile without -o",
+		},
+		{
+			desc:    "assets/missing o",
+			args:    []string{"-assets", "assets", "bar"},
+			wantRes: cliParseError,
+			wantErr: "cannot use -assets without -o",
+		},
+		{
+			desc:    "config/file",
+			args:    []string{"-config", "stitchmd.yaml", "summary.md"},
+			wantRes: cliParseError,
+			wantErr: "unexpected arguments with -config",
+		},
+		{
+			desc:    "config/job flags",
+			args:    []string{"-config", "stitchmd.yaml", "-o", "foo", "-offset", "1"},
+			wantRes: cliParseError,
+			wantErr: "cannot use -o, -offset with -config",
+		},
+		{
+			desc:    "format/unknown",
+			args:    []string{"-format", "pdf", "bar"},
+			wantRes: cliParseError,
+			wantErr: "must be one of 'markdown', 'html'",
+		},
+		{
+			desc:    "slug/unknown",
+			args:    []string{"-slug", "bitbucket", "bar"},
+			wantRes: cliParseError,
+			wantErr: "must be one of 'github', 'gitlab', 'pandoc', 'hugo'",
+		},
+		{
+			desc:    "var/no value",
+			args:    []string{"-var", "Version", "bar"},
+			wantRes: cliParseError,
+			wantErr: "must be in the form NAME=VALUE",
+		},
+		{
+			desc:    "var/bad name",
+			args:    []string{"-var", "foo-bar=baz", "bar"},
+			wantRes: cliParseError,
+			wantErr: `invalid variable name "foo-bar"`,
+		},
 		{
 			desc:    "too many args",
 			args:    []string{"-o", "foo", "bar", "baz"},
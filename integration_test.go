@@ -10,6 +10,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.abhg.dev/stitchmd/stitchmd"
 	"gopkg.in/yaml.v3"
 )
 
@@ -22,10 +23,27 @@ func TestIntegration_e2e(t *testing.T) {
 		Files map[string]string `yaml:"files,omitempty"`
 		Want  string            `yaml:"want"`
 
-		Offset  int    `yaml:"offset"`  // -offset
-		NoTOC   bool   `yaml:"no-toc"`  // -no-toc
-		Preface string `yaml:"preface"` // -preface
-		Unsafe  bool   `yaml:"unsafe"`  // -unsafe
+		// Expected warnings printed to stderr, if any.
+		Stderr string `yaml:"stderr"`
+
+		// Other files expected to be written,
+		// relative to the test directory.
+		WantFiles map[string]string `yaml:"wantFiles,omitempty"`
+
+		Offset   int    `yaml:"offset"`    // -offset
+		NoTOC    bool   `yaml:"no-toc"`    // -no-toc
+		Number   bool   `yaml:"number"`    // -number
+		Nav      bool   `yaml:"nav"`       // -nav
+		TOCDepth int    `yaml:"toc-depth"` // -toc-depth
+		TOCStyle string `yaml:"toc-style"` // -toc-style
+		Preface  string `yaml:"preface"`   // -preface
+		Unsafe   bool   `yaml:"unsafe"`    // -unsafe
+		Format   string `yaml:"format"`    // -format
+		Slug     string `yaml:"slug"`      // -slug
+		Profile  string `yaml:"profile"`   // -profile
+
+		Vars   map[string]string `yaml:"vars"`   // -var
+		Assets string            `yaml:"assets"` // -assets, relative to dir
 
 		// Directory to run the command in.
 		// summary and preface are stored in this directory.
@@ -79,6 +97,26 @@ func TestIntegration_e2e(t *testing.T) {
 				require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
 			}
 
+			var format stitchmd.Format
+			if tt.Format != "" {
+				require.NoError(t, format.Set(tt.Format))
+			}
+
+			var assets string
+			if tt.Assets != "" {
+				assets = filepath.Join(cwd, filepath.FromSlash(tt.Assets))
+			}
+
+			var slug stitchmd.SlugStyle
+			if tt.Slug != "" {
+				require.NoError(t, slug.Set(tt.Slug))
+			}
+
+			var tocStyle stitchmd.TOCStyle
+			if tt.TOCStyle != "" {
+				require.NoError(t, tocStyle.Set(tt.TOCStyle))
+			}
+
 			var stdout, stderr bytes.Buffer
 			defer func() {
 				if t.Failed() {
@@ -98,19 +136,34 @@ func TestIntegration_e2e(t *testing.T) {
 			}
 
 			require.NoError(t, cmd.run(&params{
-				Input:   input,
-				Output:  output,
-				Offset:  tt.Offset,
-				NoTOC:   tt.NoTOC,
-				Preface: preface,
-				Unsafe:  tt.Unsafe,
+				Input:    input,
+				Output:   output,
+				Offset:   tt.Offset,
+				NoTOC:    tt.NoTOC,
+				TOCDepth: tt.TOCDepth,
+				TOCStyle: tocStyle,
+				Number:   tt.Number,
+				Nav:      tt.Nav,
+				Preface:  preface,
+				Unsafe:   tt.Unsafe,
+				Format:   format,
+				Slug:     slug,
+				Profile:  tt.Profile,
+				Vars:     tt.Vars,
+				Assets:   assets,
 			}))
 
 			got, err := os.ReadFile(output)
 			require.NoError(t, err)
 
 			assert.Equal(t, tt.Want, string(got))
-			assert.Empty(t, stderr.String(), "stderr")
+			for filename, want := range tt.WantFiles {
+				got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(filename)))
+				if assert.NoError(t, err, "read %v", filename) {
+					assert.Equal(t, want, string(got), "contents of %v", filename)
+				}
+			}
+			assert.Equal(t, tt.Stderr, stderr.String(), "stderr")
 			assert.Empty(t, stdout.String(), "stdout")
 		})
 	}
@@ -25,6 +25,13 @@ func NewErrorList(info Positioner) *ErrorList {
 	return newErrorList(info, OffsetOf)
 }
 
+// NewInlineErrorList builds an ErrorList
+// that reports positions of inline nodes with [InlineOffsetOf]
+// instead of using the position of their parent block.
+func NewInlineErrorList(info Positioner) *ErrorList {
+	return newErrorList(info, InlineOffsetOf)
+}
+
 func newErrorList(info Positioner, offsetOf func(ast.Node) int) *ErrorList {
 	return &ErrorList{info: info, offsetOf: offsetOf}
 }
@@ -48,7 +55,8 @@ func (el *ErrorList) Len() int {
 // Err returns an error that contains all errors in the list
 // or nil if the list is empty.
 //
-// The errors are sorted by position.
+// The errors are sorted by position,
+// and each is an [*Error] holding that position.
 func (el *ErrorList) Err() error {
 	if len(el.errs) == 0 {
 		return nil
@@ -58,14 +66,45 @@ func (el *ErrorList) Err() error {
 		return el.errs[i].Offset < el.errs[j].Offset
 	})
 
+	lines, _ := el.info.(lineReader)
 	var errs []error
 	for _, e := range el.errs {
-		errs = append(errs,
-			fmt.Errorf("%v:%w", el.info.Position(e.Offset), e.Err))
+		err := &Error{
+			Pos: el.info.Position(e.Offset),
+			Err: e.Err,
+		}
+		if lines != nil {
+			err.Source = lines.Line(err.Pos.Line)
+		}
+		errs = append(errs, err)
 	}
 	return errors.Join(errs...)
 }
 
+// lineReader is implemented by Positioners
+// that can report the text of a line, like [Info].
+type lineReader interface {
+	Line(line int) string
+}
+
+// Error is an error at a known position in a file.
+type Error struct {
+	Pos Position // required
+	Err error    // required
+
+	// Source is the text of the line at Pos, if known.
+	Source string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v:%v", e.Pos, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
 // posError wraps an error with position information.
 type posError struct {
 	Offset int   // required
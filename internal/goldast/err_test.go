@@ -56,6 +56,29 @@ func TestErrorList(t *testing.T) {
 	assert.Equal(t, "foo:13:42:great joy: bar\nfoo:42:13:great sadness: foo", err.Error())
 	assert.ErrorIs(t, err, foo)
 	assert.ErrorIs(t, err, bar)
+
+	var posErr *Error
+	if assert.ErrorAs(t, err, &posErr) {
+		assert.Equal(t, Position{File: "foo", Line: 13, Column: 42}, posErr.Pos)
+		assert.Equal(t, "great joy: bar", posErr.Err.Error())
+	}
+}
+
+func TestErrorList_source(t *testing.T) {
+	t.Parallel()
+
+	info := infoFromContent("foo.md", []byte("# Foo\n\nHello, world.\n"))
+	para := ast.NewParagraph()
+	el := newErrorList(info, func(ast.Node) int {
+		return 10 // "lo, world."
+	})
+	el.Pushf(para, "great sadness")
+
+	var posErr *Error
+	if assert.ErrorAs(t, el.Err(), &posErr) {
+		assert.Equal(t, Position{File: "foo.md", Line: 3, Column: 4}, posErr.Pos)
+		assert.Equal(t, "Hello, world.", posErr.Source)
+	}
 }
 
 func TestPosError(t *testing.T) {
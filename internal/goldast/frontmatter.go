@@ -0,0 +1,16 @@
+package goldast
+
+import "regexp"
+
+// FrontMatterKeyOffset reports the offset of the given top-level key
+// in the front matter at the start of src.
+//
+// This works for both YAML and TOML front matter.
+// It returns 0 if the key can't be found.
+func FrontMatterKeyOffset(src []byte, key string) int {
+	pattern := regexp.MustCompile(`(?m)^["']?` + regexp.QuoteMeta(key) + `["']?[ \t]*[:=]`)
+	if loc := pattern.FindIndex(src); loc != nil {
+		return loc[0]
+	}
+	return 0
+}
@@ -0,0 +1,33 @@
+package goldast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrontMatterKeyOffset(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc string
+		src  string
+		key  string
+		want int
+	}{
+		{desc: "yaml", src: "---\ntitle: foo\n---\n", key: "title", want: 4},
+		{desc: "toml", src: "+++\ntitle = \"foo\"\n+++\n", key: "title", want: 4},
+		{desc: "quoted", src: "---\n\"my key\": foo\n---\n", key: "my key", want: 4},
+		{desc: "prefix", src: "---\ntitles: foo\ntitle: bar\n---\n", key: "title", want: 16},
+		{desc: "nested", src: "---\nfoo:\n  bar: baz\n---\n", key: "bar", want: 0},
+		{desc: "missing", src: "---\nfoo: bar\n---\n", key: "baz", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, FrontMatterKeyOffset([]byte(tt.src), tt.key))
+		})
+	}
+}
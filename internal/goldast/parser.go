@@ -48,6 +48,7 @@ func DefaultParser() parser.Parser {
 	return goldmark.New(
 		goldmark.WithExtensions(
 			extension.GFM,
+			extension.Footnote,
 			&frontmatter.Extender{},
 		),
 	).Parser()
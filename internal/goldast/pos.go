@@ -1,6 +1,7 @@
 package goldast
 
 import (
+	"bytes"
 	"fmt"
 	"sort"
 	"strconv"
@@ -40,6 +41,7 @@ var _ Positioner = (*Info)(nil)
 type Info struct {
 	file string // optional
 	size int    // size of file
+	src  []byte
 	// lines is a list of offsets at which each line starts in source.
 	//
 	// invariant: lines is always non-empty, and lines[0] is always 0.
@@ -50,7 +52,7 @@ type Info struct {
 //
 // Filename is optional.
 func infoFromContent(filename string, src []byte) *Info {
-	con := Info{file: filename, size: len(src)}
+	con := Info{file: filename, size: len(src), src: src}
 
 	var line int // first line starts at 0
 	for idx, c := range src {
@@ -71,6 +73,27 @@ func (c *Info) Filename() string {
 	return c.file
 }
 
+// Line returns the text of the given line in the file
+// without the trailing newline.
+// Lines are numbered starting at 1.
+//
+// Line returns an empty string if the line is out of bounds.
+func (c *Info) Line(line int) string {
+	if line < 1 || line > len(c.lines) {
+		return ""
+	}
+
+	start := c.lines[line-1]
+	end := c.size
+	if line < len(c.lines) {
+		end = c.lines[line]
+	}
+	text := c.src[start:end]
+	text = bytes.TrimSuffix(text, []byte("\n"))
+	text = bytes.TrimSuffix(text, []byte("\r"))
+	return string(text)
+}
+
 // Position reports the human-readable position
 // for the given offset in the file.
 //
@@ -135,3 +158,28 @@ func OffsetOf(n ast.Node) int {
 
 	return 0
 }
+
+// InlineOffsetOf reports the offset of the given node
+// in the document that it came from.
+//
+// Unlike OffsetOf, if the node is an inline node with text inside it,
+// the position of the first text is returned.
+// Otherwise, this behaves the same as OffsetOf.
+func InlineOffsetOf(n ast.Node) int {
+	if n == nil || n.Type() != ast.TypeInline {
+		return OffsetOf(n)
+	}
+
+	offset := -1
+	_ = ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if t, ok := n.(*ast.Text); ok && entering {
+			offset = t.Segment.Start
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	if offset < 0 {
+		return OffsetOf(n)
+	}
+	return offset
+}
@@ -86,6 +86,33 @@ func TestInfo_Position(t *testing.T) {
 	}
 }
 
+func TestInfo_Line(t *testing.T) {
+	t.Parallel()
+
+	info := infoFromContent("a.txt", []byte("foo\nbar\r\n\nbaz"))
+
+	tests := []struct {
+		give int
+		want string
+	}{
+		{0, ""},
+		{1, "foo"},
+		{2, "bar"},
+		{3, ""},
+		{4, "baz"},
+		{5, ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprint(tt.give), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, info.Line(tt.give))
+		})
+	}
+}
+
 func TestOffsetOf(t *testing.T) {
 	t.Parallel()
 
@@ -137,3 +164,50 @@ func TestOffsetOf(t *testing.T) {
 		assert.Equal(t, 5, OffsetOf(n))
 	})
 }
+
+func TestInlineOffsetOf(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Zero(t, InlineOffsetOf(nil))
+	})
+
+	segs := text.NewSegments()
+	segs.Append(text.NewSegment(5, 20))
+	para := ast.NewParagraph()
+	para.SetLines(segs)
+
+	t.Run("block", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, 5, InlineOffsetOf(para))
+	})
+
+	t.Run("inline with text", func(t *testing.T) {
+		t.Parallel()
+
+		para := ast.NewParagraph()
+		para.SetLines(segs)
+
+		link := ast.NewLink()
+		link.AppendChild(link, ast.NewTextSegment(text.NewSegment(12, 15)))
+		para.AppendChild(para, ast.NewTextSegment(text.NewSegment(5, 11)))
+		para.AppendChild(para, link)
+
+		assert.Equal(t, 12, InlineOffsetOf(link))
+	})
+
+	t.Run("inline without text", func(t *testing.T) {
+		t.Parallel()
+
+		para := ast.NewParagraph()
+		para.SetLines(segs)
+
+		n := ast.NewString([]byte("hello"))
+		para.AppendChild(para, n)
+
+		assert.Equal(t, 5, InlineOffsetOf(n))
+	})
+}
@@ -1,19 +1,20 @@
 // Package header generates unique IDs for Markdown headings.
 package header
 
-import "strconv"
-
 // IDGen generates slugs for headings.
 // It ensures that each slug is unique.
 type IDGen struct {
-	used map[string]struct{}
+	slugger Slugger
+	used    map[string]struct{}
 }
 
 // NewIDGen builds a new ID generator
-// that enforces uniqueness of slugs.
-func NewIDGen() *IDGen {
+// that enforces uniqueness of slugs
+// generated by the given Slugger.
+func NewIDGen(slugger Slugger) *IDGen {
 	return &IDGen{
-		used: make(map[string]struct{}),
+		slugger: slugger,
+		used:    make(map[string]struct{}),
 	}
 }
 
@@ -21,11 +22,11 @@ func NewIDGen() *IDGen {
 // It reports whether a header with this title automatically gets this slug.
 // If it returns false, the caller should render an anchor for the slug.
 func (g *IDGen) GenerateID(title string) (slug string, auto bool) {
-	slug = Slug(title)
+	slug = g.slugger.Slug(title)
 	for i := 0; ; i++ {
 		slug := slug
 		if i > 0 {
-			slug = slug + "-" + strconv.Itoa(i)
+			slug = g.slugger.Dedupe(slug, i)
 		}
 		if _, ok := g.used[slug]; !ok {
 			g.used[slug] = struct{}{}
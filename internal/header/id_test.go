@@ -9,7 +9,7 @@ import (
 func TestIDGenerator(t *testing.T) {
 	t.Parallel()
 
-	g := NewIDGen()
+	g := NewIDGen(GitHub)
 
 	if slug, auto := g.GenerateID("Hello, world!"); assert.True(t, auto) {
 		assert.Equal(t, "hello-world", slug)
@@ -19,3 +19,17 @@ func TestIDGenerator(t *testing.T) {
 		assert.Equal(t, "hello-world-1", slug)
 	}
 }
+
+func TestIDGenerator_slugger(t *testing.T) {
+	t.Parallel()
+
+	g := NewIDGen(Pandoc)
+
+	if slug, auto := g.GenerateID("1. Hello, world!"); assert.True(t, auto) {
+		assert.Equal(t, "hello-world", slug)
+	}
+
+	if slug, auto := g.GenerateID("Hello world"); assert.False(t, auto) {
+		assert.Equal(t, "hello-world-1", slug)
+	}
+}
@@ -0,0 +1,152 @@
+package header
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Slugger implements the heading ID rules of a specific Markdown renderer.
+type Slugger interface {
+	// Slug turns a heading title into a slug.
+	Slug(title string) string
+
+	// Dedupe returns the n-th alternative for a slug
+	// that is already in use by another heading.
+	// n starts at 1.
+	Dedupe(slug string, n int) string
+}
+
+var (
+	// GitHub generates heading IDs the way GitHub does.
+	// Gitea and Forgejo follow the same rules.
+	GitHub Slugger = githubSlugger{}
+
+	// GitLab generates heading IDs the way GitLab does.
+	GitLab Slugger = gitlabSlugger{}
+
+	// Pandoc generates heading IDs the way Pandoc's auto_identifiers
+	// extension does.
+	Pandoc Slugger = pandocSlugger{}
+
+	// Hugo generates heading IDs the way Hugo does
+	// with its default 'github' autoHeadingIDType.
+	Hugo Slugger = hugoSlugger{}
+)
+
+// dashSuffix appends "-n" to a slug.
+// This is the de-duplication rule used by all supported renderers.
+func dashSuffix(slug string, n int) string {
+	return slug + "-" + strconv.Itoa(n)
+}
+
+type githubSlugger struct{}
+
+func (githubSlugger) Slug(title string) string {
+	return Slug(title)
+}
+
+func (githubSlugger) Dedupe(slug string, n int) string {
+	return dashSuffix(slug, n)
+}
+
+// gitlabSlugger follows the rules in GitLab's TableOfContentsFilter:
+//
+//   - convert to lowercase
+//   - remove everything except word characters, hyphens, and spaces
+//   - convert spaces to hyphens
+//   - collapse runs of hyphens into one
+type gitlabSlugger struct{}
+
+func (gitlabSlugger) Slug(title string) string {
+	var sb strings.Builder
+	lastDash := false
+	for _, r := range strings.TrimSpace(title) {
+		switch {
+		case r == ' ', r == '-':
+			if !lastDash {
+				sb.WriteRune('-')
+			}
+			lastDash = true
+
+		case isWordRune(r):
+			sb.WriteRune(unicode.ToLower(r))
+			lastDash = false
+		}
+	}
+	return sb.String()
+}
+
+func (gitlabSlugger) Dedupe(slug string, n int) string {
+	return dashSuffix(slug, n)
+}
+
+// isWordRune reports whether r matches \p{Word} in Ruby regular expressions.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) ||
+		unicode.IsMark(r) ||
+		unicode.Is(unicode.Nd, r) ||
+		unicode.Is(unicode.Pc, r)
+}
+
+// pandocSlugger follows the rules of Pandoc's auto_identifiers extension:
+//
+//   - remove all non-alphanumeric characters
+//     except underscores, hyphens, and periods
+//   - replace spaces with hyphens
+//   - convert to lowercase
+//   - remove everything up to the first letter
+//   - use "section" if nothing is left
+type pandocSlugger struct{}
+
+func (pandocSlugger) Slug(title string) string {
+	var sb strings.Builder
+	for _, r := range strings.TrimSpace(title) {
+		switch {
+		case unicode.IsSpace(r):
+			if sb.Len() > 0 {
+				sb.WriteRune('-')
+			}
+
+		case unicode.IsLetter(r):
+			sb.WriteRune(unicode.ToLower(r))
+
+		case sb.Len() == 0:
+			// Identifiers must start with a letter.
+
+		case unicode.IsNumber(r), r == '_', r == '-', r == '.':
+			sb.WriteRune(r)
+		}
+	}
+	if sb.Len() == 0 {
+		return "section"
+	}
+	return sb.String()
+}
+
+func (pandocSlugger) Dedupe(slug string, n int) string {
+	return dashSuffix(slug, n)
+}
+
+// hugoSlugger follows the rules of Hugo's 'github' autoHeadingIDType.
+// Unlike GitHub, this drops all characters
+// other than letters, digits, underscores, spaces, and hyphens.
+type hugoSlugger struct{}
+
+func (hugoSlugger) Slug(title string) string {
+	var sb strings.Builder
+	for _, r := range strings.TrimSpace(title) {
+		switch {
+		case r == ' ', r == '-':
+			sb.WriteRune('-')
+
+		case r == '_', unicode.IsLetter(r), unicode.IsDigit(r):
+			sb.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return sb.String()
+}
+
+func (hugoSlugger) Dedupe(slug string, n int) string {
+	return dashSuffix(slug, n)
+}
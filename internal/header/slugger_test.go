@@ -0,0 +1,83 @@
+package header
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSluggers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		give string
+
+		github string
+		gitlab string
+		pandoc string
+		hugo   string
+	}{
+		{
+			give:   "Hello, world!",
+			github: "hello-world",
+			gitlab: "hello-world",
+			pandoc: "hello-world",
+			hugo:   "hello-world",
+		},
+		{
+			give:   "happy 😄 emoji",
+			github: "happy--emoji",
+			gitlab: "happy-emoji",
+			pandoc: "happy--emoji",
+			hugo:   "happy--emoji",
+		},
+		{
+			give:   "Dogs?--in my house?",
+			github: "dogs--in-my-house",
+			gitlab: "dogs-in-my-house",
+			pandoc: "dogs--in-my-house",
+			hugo:   "dogs--in-my-house",
+		},
+		{
+			give:   "3. Applications",
+			github: "3-applications",
+			gitlab: "3-applications",
+			pandoc: "applications",
+			hugo:   "3-applications",
+		},
+		{
+			give:   "v1.2 release_notes",
+			github: "v12-release_notes",
+			gitlab: "v12-release_notes",
+			pandoc: "v1.2-release_notes",
+			hugo:   "v12-release_notes",
+		},
+		{
+			give:   "42",
+			github: "42",
+			gitlab: "42",
+			pandoc: "section",
+			hugo:   "42",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.give, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.github, GitHub.Slug(tt.give), "github")
+			assert.Equal(t, tt.gitlab, GitLab.Slug(tt.give), "gitlab")
+			assert.Equal(t, tt.pandoc, Pandoc.Slug(tt.give), "pandoc")
+			assert.Equal(t, tt.hugo, Hugo.Slug(tt.give), "hugo")
+		})
+	}
+}
+
+func TestSluggers_dedupe(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []Slugger{GitHub, GitLab, Pandoc, Hugo} {
+		assert.Equal(t, "foo-2", s.Dedupe("foo", 2))
+	}
+}
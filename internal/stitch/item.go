@@ -1,7 +1,9 @@
 package stitch
 
 import (
+	"net/url"
 	"path/filepath"
+	"strings"
 
 	"github.com/yuin/goldmark/ast"
 	"go.abhg.dev/stitchmd/internal/goldast"
@@ -9,11 +11,12 @@ import (
 )
 
 // Item is a single item in a section.
-// It can be a [LinkItem] or a [TextItem].
+// It can be a [LinkItem], [EmbedItem], [GlobItem], or [TextItem].
 type Item interface {
 	item() // seals the interface
 
 	ItemDepth() int
+	ItemProfiles() []string
 	Node() ast.Node
 }
 
@@ -88,6 +91,7 @@ func (p *itemTreeParser) parseItem(li *ast.ListItem) {
 		return
 	}
 
+	profiles := takeProfiles(n, p.src)
 	goldast.CombineAdjacentTexts(n, p.src)
 	switch count := n.ChildCount(); count {
 	case 0:
@@ -107,15 +111,19 @@ func (p *itemTreeParser) parseItem(li *ast.ListItem) {
 	var item Item
 	switch n := n.(type) {
 	case *ast.Link:
-		item = p.parseLinkItem(n)
+		if isGlob(string(n.Destination)) {
+			item = p.parseGlobItem(n, profiles)
+			break
+		}
+		item = p.parseLinkItem(n, profiles)
 		// TODO: separate link and external link?
 		// TODO: external link can't have children validation should be
 		// here
 	case *ast.Image:
-		item = p.parseEmbedItem(n)
+		item = p.parseEmbedItem(n, profiles)
 		// TODO: embed can't have children
 	case *ast.Text:
-		item = p.parseTextItem(n, children != nil)
+		item = p.parseTextItem(n, profiles, children != nil)
 	default:
 		p.errs.Pushf(n, "expected a link or text, got %v", n.Kind())
 		return
@@ -146,16 +154,21 @@ type LinkItem struct {
 	// Depth starts at zero for top-level items.
 	Depth int
 
+	// Profiles lists the profiles that the item is limited to.
+	// It's empty if the item is part of all profiles.
+	Profiles []string
+
 	// AST holds the original link node.
 	AST *ast.Link
 }
 
-func (p *itemTreeParser) parseLinkItem(link *ast.Link) *LinkItem {
+func (p *itemTreeParser) parseLinkItem(link *ast.Link, profiles []string) *LinkItem {
 	return &LinkItem{
-		Text:   string(goldast.Text(p.src, link)),
-		Target: filepath.ToSlash(string(link.Destination)),
-		Depth:  p.depth,
-		AST:    link,
+		Text:     string(goldast.Text(p.src, link)),
+		Target:   filepath.ToSlash(string(link.Destination)),
+		Depth:    p.depth,
+		Profiles: profiles,
+		AST:      link,
 	}
 }
 
@@ -166,12 +179,86 @@ func (i *LinkItem) ItemDepth() int {
 	return i.Depth
 }
 
+// ItemProfiles reports the profiles that the item is limited to.
+func (i *LinkItem) ItemProfiles() []string {
+	return i.Profiles
+}
+
 // Node reports the underlying AST node
 // that this item was parsed from.
 func (i *LinkItem) Node() ast.Node {
 	return i.AST
 }
 
+// GlobItem is a link item in a table of contents
+// whose target is a glob pattern matching multiple files.
+//
+//	[Foo](foo/*.md)
+//
+// The pattern uses the syntax of [path.Match].
+type GlobItem struct {
+	// Text of the item.
+	// This is the text inside the "[..]" section of the link.
+	Text string
+
+	// Pattern is the glob pattern inside the "(..)" section of the link.
+	// It's /-separated, even on Windows.
+	Pattern string
+
+	// Depth is the depth of the item in the table of contents.
+	// Depth starts at zero for top-level items.
+	Depth int
+
+	// Profiles lists the profiles that the item is limited to.
+	// It's empty if the item is part of all profiles.
+	Profiles []string
+
+	// AST holds the original link node.
+	AST *ast.Link
+}
+
+var _ Item = (*GlobItem)(nil)
+
+// isGlob reports whether the given link destination is a glob pattern.
+//
+// Only '*' is used to detect globs
+// because '?' is common in URLs.
+func isGlob(dest string) bool {
+	if !strings.Contains(dest, "*") {
+		return false
+	}
+	u, err := url.Parse(dest)
+	return err != nil || (u.Scheme == "" && u.Host == "")
+}
+
+func (p *itemTreeParser) parseGlobItem(link *ast.Link, profiles []string) *GlobItem {
+	return &GlobItem{
+		Text:     string(goldast.Text(p.src, link)),
+		Pattern:  filepath.ToSlash(string(link.Destination)),
+		Depth:    p.depth,
+		Profiles: profiles,
+		AST:      link,
+	}
+}
+
+func (*GlobItem) item() {}
+
+// ItemDepth reports the depth of the item in the table of contents.
+func (i *GlobItem) ItemDepth() int {
+	return i.Depth
+}
+
+// ItemProfiles reports the profiles that the item is limited to.
+func (i *GlobItem) ItemProfiles() []string {
+	return i.Profiles
+}
+
+// Node reports the underlying AST node
+// that this item was parsed from.
+func (i *GlobItem) Node() ast.Node {
+	return i.AST
+}
+
 // EmbedItem is a reference to another summary file
 // intended to be nested in the table of contents.
 //
@@ -189,18 +276,23 @@ type EmbedItem struct {
 	// Depth is the depth of this item in the table of contents.
 	Depth int
 
+	// Profiles lists the profiles that the item is limited to.
+	// It's empty if the item is part of all profiles.
+	Profiles []string
+
 	// AST holds the original node.
 	AST ast.Node
 }
 
 var _ Item = (*EmbedItem)(nil)
 
-func (p *itemTreeParser) parseEmbedItem(embed *ast.Image) *EmbedItem {
+func (p *itemTreeParser) parseEmbedItem(embed *ast.Image, profiles []string) *EmbedItem {
 	return &EmbedItem{
-		Text:   string(goldast.Text(p.src, embed)),
-		Target: filepath.ToSlash(string(embed.Destination)),
-		Depth:  p.depth,
-		AST:    embed,
+		Text:     string(goldast.Text(p.src, embed)),
+		Target:   filepath.ToSlash(string(embed.Destination)),
+		Depth:    p.depth,
+		Profiles: profiles,
+		AST:      embed,
 	}
 }
 
@@ -211,6 +303,11 @@ func (i *EmbedItem) ItemDepth() int {
 	return i.Depth
 }
 
+// ItemProfiles reports the profiles that this embed request is limited to.
+func (i *EmbedItem) ItemProfiles() []string {
+	return i.Profiles
+}
+
 // Node returns the original AST node in the summary file
 // that this item is built from.
 func (i *EmbedItem) Node() ast.Node {
@@ -228,20 +325,25 @@ type TextItem struct {
 	// Depth starts at zero for top-level items.
 	Depth int
 
+	// Profiles lists the profiles that the item is limited to.
+	// It's empty if the item is part of all profiles.
+	Profiles []string
+
 	// AST holds the original text node.
 	AST *ast.Text
 }
 
-func (p *itemTreeParser) parseTextItem(text *ast.Text, hasChildren bool) *TextItem {
+func (p *itemTreeParser) parseTextItem(text *ast.Text, profiles []string, hasChildren bool) *TextItem {
 	if !hasChildren {
 		p.errs.Pushf(text, "text item must have children")
 		return nil
 	}
 
 	return &TextItem{
-		Text:  string(goldast.Text(p.src, text)),
-		Depth: p.depth,
-		AST:   text,
+		Text:     string(goldast.Text(p.src, text)),
+		Depth:    p.depth,
+		Profiles: profiles,
+		AST:      text,
 	}
 }
 
@@ -252,6 +354,11 @@ func (i *TextItem) ItemDepth() int {
 	return i.Depth
 }
 
+// ItemProfiles reports the profiles that the item is limited to.
+func (i *TextItem) ItemProfiles() []string {
+	return i.Profiles
+}
+
 // Node reports the underlying AST node
 // that this item was parsed from.
 func (i *TextItem) Node() ast.Node {
@@ -35,3 +35,18 @@ func TestTextItem_accessors(t *testing.T) {
 	assert.Equal(t, 3, item.ItemDepth())
 	assert.True(t, item.Node() == node)
 }
+
+func TestGlobItem_accessors(t *testing.T) {
+	t.Parallel()
+
+	link := ast.NewLink()
+	item := GlobItem{
+		Text:    "foo",
+		Pattern: "foo/*.md",
+		Depth:   3,
+		AST:     link,
+	}
+
+	assert.Equal(t, 3, item.ItemDepth())
+	assert.True(t, item.Node() == link)
+}
@@ -0,0 +1,85 @@
+package stitch
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// Items and blocks of content may be limited to certain profiles
+// with an HTML comment listing the profile names.
+//
+//	- [Support contacts](contacts.md) <!-- profile: internal -->
+//
+// Items without such a comment are part of all profiles.
+
+var (
+	_profileStart = regexp.MustCompile(`^<!--\s*profile:\s*(.*?)\s*-->$`)
+	_profileEnd   = regexp.MustCompile(`^<!--\s*end\s+profile\s*-->$`)
+)
+
+// ParseProfileStart parses a comment in the form,
+//
+//	<!-- profile: foo, bar -->
+//
+// and reports the profile names listed in it.
+// It returns false if the text is not such a comment.
+func ParseProfileStart(s string) (profiles []string, ok bool) {
+	m := _profileStart.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return nil, false
+	}
+
+	profiles = strings.FieldsFunc(m[1], func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	return profiles, true
+}
+
+// IsProfileEnd reports whether the given text is a comment in the form,
+//
+//	<!-- end profile -->
+func IsProfileEnd(s string) bool {
+	return _profileEnd.MatchString(strings.TrimSpace(s))
+}
+
+// MatchProfile reports whether something limited to the given profiles
+// should be included when building the given profile.
+//
+// Things that aren't limited to any profiles are always included.
+// Otherwise, they're included only if the profile is one of them.
+func MatchProfile(profiles []string, profile string) bool {
+	return len(profiles) == 0 || slices.Contains(profiles, profile)
+}
+
+// takeProfiles removes a trailing profile comment
+// from the children of the given node, if any,
+// along with the whitespace before it.
+// It reports the profiles listed in the comment.
+func takeProfiles(n ast.Node, src []byte) []string {
+	html, ok := n.LastChild().(*ast.RawHTML)
+	if !ok || n.ChildCount() < 2 {
+		return nil
+	}
+
+	var comment strings.Builder
+	for i := 0; i < html.Segments.Len(); i++ {
+		seg := html.Segments.At(i)
+		comment.Write(seg.Value(src))
+	}
+	profiles, ok := ParseProfileStart(comment.String())
+	if !ok {
+		return nil
+	}
+	n.RemoveChild(n, html)
+
+	if t, ok := n.LastChild().(*ast.Text); ok {
+		t.Segment = t.Segment.TrimRightSpace(src)
+		if t.Segment.IsEmpty() {
+			n.RemoveChild(n, t)
+		}
+	}
+	return profiles
+}
@@ -0,0 +1,56 @@
+package stitch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProfileStart(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		give   string
+		want   []string
+		wantOK bool
+	}{
+		{give: "<!-- profile: internal -->", want: []string{"internal"}, wantOK: true},
+		{give: "<!--profile:internal-->", want: []string{"internal"}, wantOK: true},
+		{give: "<!-- profile: a, b c -->\n", want: []string{"a", "b", "c"}, wantOK: true},
+		{give: "<!-- profile: -->", want: []string{}, wantOK: true},
+		{give: "<!-- end profile -->"},
+		{give: "<!-- profiles: a -->"},
+		{give: "<!-- foo --> <!-- profile: a -->"},
+		{give: "profile: a"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.give, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := ParseProfileStart(tt.give)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestIsProfileEnd(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsProfileEnd("<!-- end profile -->"))
+	assert.True(t, IsProfileEnd("<!--end  profile-->\n"))
+	assert.False(t, IsProfileEnd("<!-- end -->"))
+	assert.False(t, IsProfileEnd("<!-- profile: end -->"))
+}
+
+func TestMatchProfile(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, MatchProfile(nil, ""))
+	assert.True(t, MatchProfile(nil, "internal"))
+	assert.True(t, MatchProfile([]string{"a", "internal"}, "internal"))
+	assert.False(t, MatchProfile([]string{"internal"}, ""))
+	assert.False(t, MatchProfile([]string{"internal"}, "public"))
+}
@@ -47,6 +47,16 @@ func TestParseSummary(t *testing.T) {
 		}
 	}
 
+	globItem := func(depth int, text, pattern string) *tree.Node[Item] {
+		return &tree.Node[Item]{
+			Value: &GlobItem{
+				Text:    text,
+				Pattern: pattern,
+				Depth:   depth,
+			},
+		}
+	}
+
 	section := func(lvl int, title string, items ...*tree.Node[Item]) *Section {
 		var stitle *SectionTitle
 		if len(title) > 0 {
@@ -170,6 +180,59 @@ func TestParseSummary(t *testing.T) {
 				section(0, "", embedItem(0, "foo", "foo.md")),
 			),
 		},
+		{
+			desc: "glob",
+			give: unlines(
+				"- [foo](foo.md)",
+				"    - [ADRs](adr/*.md)",
+			),
+			want: toc(
+				section(0, "",
+					linkItem(0, "foo", "foo.md",
+						globItem(1, "ADRs", "adr/*.md"))),
+			),
+		},
+		{
+			desc: "external link with asterisk",
+			give: "- [foo](https://example.com/*)",
+			want: toc(
+				section(0, "", linkItem(0, "foo", "https://example.com/*")),
+			),
+		},
+		{
+			desc: "profiles",
+			give: unlines(
+				"- [foo](foo.md) <!-- profile: internal -->",
+				"- bar <!-- profile: a, b -->",
+				"    - ![baz](baz.md) <!-- profile:beta-->",
+			),
+			want: toc(
+				section(0, "",
+					&tree.Node[Item]{
+						Value: &LinkItem{
+							Text:     "foo",
+							Target:   "foo.md",
+							Profiles: []string{"internal"},
+						},
+					},
+					&tree.Node[Item]{
+						Value: &TextItem{
+							Text:     "bar",
+							Profiles: []string{"a", "b"},
+						},
+						List: tree.List[Item]{
+							{
+								Value: &EmbedItem{
+									Text:     "baz",
+									Target:   "baz.md",
+									Depth:    1,
+									Profiles: []string{"beta"},
+								},
+							},
+						},
+					}),
+			),
+		},
 	}
 
 	for _, tt := range tests {
@@ -197,6 +260,8 @@ func TestParseSummary(t *testing.T) {
 						i.AST = nil
 					case *EmbedItem:
 						i.AST = nil
+					case *GlobItem:
+						i.AST = nil
 					default:
 						t.Fatalf("unexpected item type %T", i)
 					}
@@ -7,30 +7,42 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
+	"maps"
 	"os"
 	"path"
 	"path/filepath"
 
-	mdfmt "github.com/Kunde21/markdownfmt/v3/markdown"
 	"github.com/mattn/go-colorable"
 	isatty "github.com/mattn/go-isatty"
 	"github.com/pkg/diff"
 	"github.com/pkg/diff/write"
-	"github.com/yuin/goldmark/parser"
-	"github.com/yuin/goldmark/util"
 	"go.abhg.dev/stitchmd/internal/errdefer"
-	"go.abhg.dev/stitchmd/internal/goldast"
-	"go.abhg.dev/stitchmd/internal/rawhtml"
-	"go.abhg.dev/stitchmd/internal/stitch"
+	"go.abhg.dev/stitchmd/stitchmd"
 )
 
 var _version = "dev"
 
+// Exit codes reported by the program.
+const (
+	_exitOK    = 0
+	_exitError = 1
+
+	// The output file is out of date.
+	// Reported only in -check mode.
+	_exitStale = 2
+)
+
+// errStale is returned by run when -check is used
+// and the output file is out of date.
+// The stale file has already been reported by the time this is returned.
+var errStale = errors.New("output is out of date")
+
 func main() {
 	cmd := mainCmd{
 		Stdin:  os.Stdin,
@@ -49,28 +61,98 @@ type mainCmd struct {
 
 	Getwd  func() (string, error) // required (os.Getwd)
 	Getenv func(string) string    // required (os.Getenv)
+
+	// Problems reported so far, for -sarif.
+	diagnostics []diagnostic
 }
 
 func (cmd *mainCmd) Run(args []string) (exitCode int) {
 	opts, res := (&cliParser{
 		Stdout: cmd.Stdout,
 		Stderr: cmd.Stderr,
+		Record: cmd.recordDiagnostics,
 	}).Parse(args)
 	switch res {
 	case cliParseSuccess:
 		// continue
 	case cliParseHelp:
-		return 0
+		return _exitOK
 	case cliParseError:
-		return 1
+		if opts != nil && len(opts.SARIF) > 0 {
+			if err := cmd.writeSARIF(opts.SARIF); err != nil {
+				fmt.Fprintln(cmd.Stderr, "stitchmd: -sarif:", err)
+			}
+		}
+		return _exitError
+	}
+
+	var run func(*params) error
+	switch {
+	case len(opts.Config) > 0:
+		run = cmd.runConfig
+	case opts.Watch:
+		run = func(opts *params) error {
+			return cmd.watchUntilInterrupted([]*params{opts})
+		}
+	default:
+		run = cmd.run
 	}
 
-	if err := cmd.run(opts); err != nil {
-		fmt.Fprintln(cmd.Stderr, "stitchmd:", err)
-		return 1
+	err := run(opts)
+	if len(opts.SARIF) > 0 {
+		// The report is written even if the run failed
+		// because that's when it's most useful.
+		if sarifErr := cmd.writeSARIF(opts.SARIF); sarifErr != nil {
+			fmt.Fprintln(cmd.Stderr, "stitchmd: -sarif:", sarifErr)
+			if err == nil {
+				return _exitError
+			}
+		}
+	}
+
+	if err != nil {
+		if errors.Is(err, errStale) {
+			return _exitStale
+		}
+		if opts.Diagnostics == diagnosticsJSON {
+			(&diagnosticLogger{
+				W:      cmd.Stderr,
+				Format: opts.Diagnostics,
+			}).Errors("", []error{err})
+		} else {
+			fmt.Fprintln(cmd.Stderr, "stitchmd:", err)
+		}
+		return _exitError
+	}
+
+	return _exitOK
+}
+
+// diagnosticsDir returns the directory
+// that paths in problems found in inputDir are reported relative to.
+// This is inputDir made relative to cwd if possible.
+func diagnosticsDir(cwd, inputDir string) string {
+	if filepath.IsAbs(inputDir) {
+		if rel, err := filepath.Rel(cwd, inputDir); err == nil {
+			return rel
+		}
 	}
+	return inputDir
+}
+
+// recordDiagnostics records a problem for the -sarif report.
+func (cmd *mainCmd) recordDiagnostics(d diagnostic) {
+	cmd.diagnostics = append(cmd.diagnostics, d)
+}
 
-	return 0
+// writeSARIF writes the problems reported so far
+// to a SARIF log at the given path.
+func (cmd *mainCmd) writeSARIF(path string) error {
+	cwd, err := cmd.Getwd()
+	if err != nil {
+		return fmt.Errorf("get current directory: %w", err)
+	}
+	return writeSARIFTo(path, cwd, cmd.diagnostics)
 }
 
 func (cmd *mainCmd) shouldColor(opts *params) bool {
@@ -86,7 +168,18 @@ func (cmd *mainCmd) shouldColor(opts *params) bool {
 	}
 }
 
-func (cmd *mainCmd) run(opts *params) (err error) {
+func (cmd *mainCmd) run(opts *params) error {
+	_, err := cmd.stitch(opts)
+	return err
+}
+
+// stitch generates the output for the given parameters once.
+//
+// It reports the paths of the files that were read while collecting
+// the Markdown files listed in the summary and bundling assets,
+// even if generation failed after that point.
+// The summary and preface are not included in this list.
+func (cmd *mainCmd) stitch(opts *params) (inputs []string, err error) {
 	shouldColor := cmd.shouldColor(opts)
 	if shouldColor {
 		cmd.Stdout = makeColorable(cmd.Stdout)
@@ -100,7 +193,7 @@ func (cmd *mainCmd) run(opts *params) (err error) {
 		filename = opts.Input
 		f, err := os.Open(opts.Input)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		defer errdefer.Closef(&err, f, "close %q", opts.Input)
 		input = f
@@ -111,18 +204,28 @@ func (cmd *mainCmd) run(opts *params) (err error) {
 		var err error
 		preface, err = os.ReadFile(opts.Preface)
 		if err != nil {
-			return fmt.Errorf("-preface: %w", err)
+			return nil, fmt.Errorf("-preface: %w", err)
 		}
+	}
 
-		// Ensure trailing newline.
-		if len(preface) > 0 && preface[len(preface)-1] != '\n' {
-			preface = append(preface, '\n')
+	// Variables set explicitly take precedence over the variable file,
+	// which takes precedence over the front matter of the summary.
+	vars := maps.Clone(opts.SummaryVars)
+	if vars == nil {
+		vars = make(map[string]string)
+	}
+	if len(opts.VarFile) > 0 {
+		fileVars, err := loadVarFile(opts.VarFile)
+		if err != nil {
+			return nil, fmt.Errorf("-var-file: %w", err)
 		}
+		maps.Copy(vars, fileVars)
 	}
+	maps.Copy(vars, opts.Vars)
 
 	cwd, err := cmd.Getwd()
 	if err != nil {
-		return fmt.Errorf("get current directory: %w", err)
+		return nil, fmt.Errorf("get current directory: %w", err)
 	}
 	// Input and output directories are determined in the following order:
 	//
@@ -148,33 +251,55 @@ func (cmd *mainCmd) run(opts *params) (err error) {
 	if len(opts.Input) > 0 {
 		filenameRel, err = filepath.Rel(inputDir, filename)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		filenameRel = filepath.ToSlash(filenameRel)
 	}
 
+	// /-separated relative path to the output file from the input directory.
+	// Empty if the output is stdout or it can't be expressed that way.
+	var outputRel string
+	if len(opts.Output) > 0 {
+		if rel, err := filepath.Rel(inputDir, opts.Output); err == nil {
+			outputRel = filepath.ToSlash(rel)
+		}
+	}
+
 	output := cmd.Stdout
 	if len(opts.Output) > 0 {
-		if opts.Diff {
-			dw, err := newDiffWriter(opts.Output, shouldColor)
-			if err != nil {
-				return fmt.Errorf("-diff: %w", err)
+		if opts.Diff || opts.Check {
+			// Don't shadow err: it's inspected by the deferred function.
+			dw, dwErr := newDiffWriter(opts.Output, shouldColor)
+			if dwErr != nil {
+				if opts.Check {
+					return nil, fmt.Errorf("-check: %w", dwErr)
+				}
+				return nil, fmt.Errorf("-diff: %w", dwErr)
 			}
 			defer func() {
-				if err := dw.Diff(cmd.Stdout); err != nil {
-					log.Printf("Error writing diff: %v", err)
+				if opts.Diff {
+					if err := dw.Diff(cmd.Stdout); err != nil {
+						log.Printf("Error writing diff: %v", err)
+					}
+				}
+
+				// Don't report staleness if we failed to generate
+				// the output in the first place.
+				if opts.Check && err == nil && dw.Changed() {
+					log.Printf("%v is out of date", opts.Output)
+					err = errStale
 				}
 			}()
 			output = dw
 		} else {
 			outDir := filepath.Dir(opts.Output)
 			if err := os.MkdirAll(outDir, 0o755); err != nil {
-				return fmt.Errorf("create output directory: %w", err)
+				return nil, fmt.Errorf("create output directory: %w", err)
 			}
 
 			f, err := os.Create(opts.Output)
 			if err != nil {
-				return fmt.Errorf("create output: %w", err)
+				return nil, fmt.Errorf("create output: %w", err)
 			}
 			defer errdefer.Closef(&err, f, "close %q", opts.Output)
 			output = f
@@ -188,36 +313,22 @@ func (cmd *mainCmd) run(opts *params) (err error) {
 	{
 		outAbs, err := filepath.Abs(outputDir)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		inAbs, err := filepath.Abs(inputDir)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		inputRel, err = filepath.Rel(outAbs, inAbs)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	src, err := io.ReadAll(input)
 	if err != nil {
-		return fmt.Errorf("input: %w", err)
-	}
-
-	mdParser := goldast.DefaultParser()
-	mdParser.AddOptions(
-		parser.WithASTTransformers(
-			util.Prioritized(&rawhtml.Transformer{}, 100),
-		),
-	)
-
-	f := goldast.Parse(mdParser, filenameRel, src)
-	summary, err := stitch.ParseSummary(f)
-	if err != nil {
-		log.Println(err)
-		return errors.New("error parsing summary")
+		return nil, fmt.Errorf("input: %w", err)
 	}
 
 	collectFS := os.DirFS(inputDir)
@@ -225,41 +336,122 @@ func (cmd *mainCmd) run(opts *params) (err error) {
 		collectFS = unsafeDirFS(inputDir)
 	}
 
-	var collectorStack []string
-	if len(filenameRel) > 0 {
-		collectorStack = append(collectorStack, filenameRel)
+	var assetsRel string
+	if len(opts.Assets) > 0 {
+		outAbs, err := filepath.Abs(outputDir)
+		if err != nil {
+			return nil, err
+		}
+		assetsAbs, err := filepath.Abs(opts.Assets)
+		if err != nil {
+			return nil, err
+		}
+		assetsRel, err = filepath.Rel(outAbs, assetsAbs)
+		if err != nil {
+			return nil, fmt.Errorf("-assets: %w", err)
+		}
+		assetsRel = filepath.ToSlash(assetsRel)
 	}
 
-	coll, err := (&collector{
-		FS:     collectFS,
-		Parser: mdParser,
-		Stack:  collectorStack,
-	}).Collect(f.Info, summary)
+	diag := &diagnosticLogger{
+		W:      cmd.Stderr,
+		Format: opts.Diagnostics,
+		Color:  shouldColor,
+		Dir:    diagnosticsDir(cwd, inputDir),
+		Record: cmd.recordDiagnostics,
+	}
+	if shouldColor {
+		diag.W = makeColorable(cmd.Stderr)
+	}
+	res, err := stitchmd.Run(context.Background(), stitchmd.Options{
+		FS:           collectFS,
+		Summary:      src,
+		SummaryPath:  filenameRel,
+		OutputPath:   outputRel,
+		InputRelPath: filepath.ToSlash(inputRel),
+		// Links are only checked for existence,
+		// so it's okay for them to point outside the input directory
+		// even without -unsafe.
+		LinkFS:     unsafeDirFS(inputDir),
+		AssetsPath: assetsRel,
+		Preface:    preface,
+		Offset:     opts.Offset,
+		NoTOC:      opts.NoTOC,
+		TOCDepth:   opts.TOCDepth,
+		TOCStyle:   opts.TOCStyle,
+		Number:     opts.Number,
+		Nav:        opts.Nav,
+		Format:     opts.Format,
+		Slug:       opts.Slug,
+		Profile:    opts.Profile,
+		Vars:       vars,
+		Strict:     opts.Strict,
+		Warn: func(err *stitchmd.InputError) {
+			diag.Warnings(false, err.Code, err.Errs)
+		},
+	})
+	for _, p := range res.Inputs {
+		inputs = append(inputs, filepath.Join(inputDir, filepath.FromSlash(p)))
+	}
 	if err != nil {
-		log.Println(err)
-		return errors.New("error reading markdown")
+		// Report the individual problems,
+		// and fail with the short description.
+		var inputErr *stitchmd.InputError
+		if errors.As(err, &inputErr) {
+			diag.Errors(inputErr.Code, inputErr.Errs)
+			return inputs, errors.New(inputErr.Msg)
+		}
+		return inputs, err
 	}
 
-	(&transformer{
-		Log:          log,
-		Offset:       opts.Offset,
-		InputRelPath: filepath.ToSlash(inputRel),
-		SummaryFile:  f,
-	}).Transform(coll)
+	if _, err := output.Write(res.Output); err != nil {
+		return inputs, err
+	}
 
-	render := mdfmt.NewRenderer()
-	render.AddMarkdownOptions(
-		mdfmt.WithSoftWraps(),
-	)
+	// Like the dependency file below,
+	// assets are only written if we wrote the output.
+	if len(opts.Assets) > 0 && !opts.Diff && !opts.Check {
+		if err := res.WriteAssets(opts.Assets); err != nil {
+			return inputs, fmt.Errorf("-assets: %w", err)
+		}
+	}
+
+	// The dependency file describes the output file,
+	// so don't write it if we didn't write the output.
+	if len(opts.DepFile) > 0 && !opts.Diff && !opts.Check {
+		deps := make([]string, 0, len(inputs)+3)
+		if len(opts.Input) > 0 {
+			deps = append(deps, opts.Input)
+		}
+		if len(opts.Preface) > 0 {
+			deps = append(deps, opts.Preface)
+		}
+		if len(opts.VarFile) > 0 {
+			deps = append(deps, opts.VarFile)
+		}
+		deps = append(deps, inputs...)
 
-	g := &generator{
-		Preface:  preface,
-		W:        output,
-		Renderer: render,
-		Log:      log,
-		NoTOC:    opts.NoTOC,
+		if err := writeDepfileTo(opts.DepFile, opts.Output, deps); err != nil {
+			return inputs, fmt.Errorf("-depfile: %w", err)
+		}
 	}
-	return g.Generate(f.Source, coll)
+
+	return inputs, nil
+}
+
+// writeDepfileTo writes a dependency file to the given path.
+func writeDepfileTo(path, target string, deps []string) (err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer errdefer.Closef(&err, f, "close %q", path)
+
+	return writeDepfile(f, target, deps)
 }
 
 // unsafeDirFS is a minimal FS implementation
@@ -304,8 +496,14 @@ func (dw *diffWriter) Write(p []byte) (int, error) {
 	return dw.new.Write(p)
 }
 
+// Changed reports whether the data written to the writer so far
+// differs from the reference.
+func (dw *diffWriter) Changed() bool {
+	return !bytes.Equal(dw.old, dw.new.Bytes())
+}
+
 func (dw *diffWriter) Diff(w io.Writer) error {
-	if bytes.Equal(dw.old, dw.new.Bytes()) {
+	if !dw.Changed() {
 		return nil
 	}
 
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"os"
 	"path/filepath"
@@ -267,6 +268,350 @@ func TestMain_diffColor(t *testing.T) {
 	assert.Contains(t, stdout.String(), "\x1b[31m") // red
 }
 
+func TestMain_check(t *testing.T) {
+	t.Parallel()
+
+	setup := func(t *testing.T, old string) (dir string) {
+		dir = t.TempDir()
+		require.NoError(t,
+			os.WriteFile(filepath.Join(dir, "summary.md"), []byte("- [foo](foo.md)"), 0o644))
+		require.NoError(t,
+			os.WriteFile(filepath.Join(dir, "foo.md"), []byte("# Foo\n\nstuff\n"), 0o644))
+		require.NoError(t,
+			os.WriteFile(filepath.Join(dir, "out.md"), []byte(old), 0o644))
+		return dir
+	}
+
+	run := func(t *testing.T, dir string, args ...string) (exitCode int, stdout, stderr string) {
+		var stdoutBuf, stderrBuf bytes.Buffer
+		exitCode = (&mainCmd{
+			Stdin:  bytes.NewReader(nil),
+			Stdout: &stdoutBuf,
+			Stderr: &stderrBuf,
+			Getwd: func() (string, error) {
+				return dir, nil
+			},
+			Getenv: nopGetenv,
+		}).Run(append(args,
+			"-o", filepath.Join(dir, "out.md"),
+			filepath.Join(dir, "summary.md"),
+		))
+		return exitCode, stdoutBuf.String(), stderrBuf.String()
+	}
+
+	const upToDate = "- [foo](#foo)\n\n# Foo\n\nstuff\n"
+
+	t.Run("up to date", func(t *testing.T) {
+		t.Parallel()
+
+		dir := setup(t, upToDate)
+		exitCode, stdout, stderr := run(t, dir, "-check")
+		assert.Equal(t, _exitOK, exitCode)
+		assert.Empty(t, stdout, "stdout")
+		assert.Empty(t, stderr, "stderr")
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		t.Parallel()
+
+		dir := setup(t, "old")
+		exitCode, stdout, stderr := run(t, dir, "-check")
+		assert.Equal(t, _exitStale, exitCode)
+		assert.Empty(t, stdout, "stdout")
+		assert.Equal(t, filepath.Join(dir, "out.md")+" is out of date\n", stderr)
+
+		// The file must not be modified.
+		got, err := os.ReadFile(filepath.Join(dir, "out.md"))
+		require.NoError(t, err)
+		assert.Equal(t, "old", string(got))
+	})
+
+	t.Run("stale with diff", func(t *testing.T) {
+		t.Parallel()
+
+		dir := setup(t, "old")
+		exitCode, stdout, stderr := run(t, dir, "-check", "-d")
+		assert.Equal(t, _exitStale, exitCode)
+		assert.Contains(t, stdout, "-old")
+		assert.Contains(t, stdout, "+# Foo")
+		assert.Contains(t, stderr, "is out of date")
+	})
+
+	t.Run("generation error", func(t *testing.T) {
+		t.Parallel()
+
+		dir := setup(t, "old")
+		require.NoError(t, os.Remove(filepath.Join(dir, "foo.md")))
+
+		exitCode, _, stderr := run(t, dir, "-check")
+		assert.Equal(t, _exitError, exitCode)
+		assert.Contains(t, stderr, "error reading markdown")
+		assert.NotContains(t, stderr, "is out of date")
+	})
+}
+
+func TestMain_depfile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile := func(name, contents string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	}
+
+	writeFile("doc/SUMMARY.md", "- [foo](foo.md)\n- ![bar](bar/SUMMARY.md)\n")
+	writeFile("doc/foo.md", "# Foo\n")
+	writeFile("doc/bar/SUMMARY.md", "- [baz](baz.md)\n")
+	writeFile("doc/bar/baz.md", "# Baz\n")
+	writeFile("doc/preface.txt", "<!-- generated -->\n")
+
+	var stderr bytes.Buffer
+	exitCode := (&mainCmd{
+		Stdin:  bytes.NewReader(nil),
+		Stdout: io.Discard,
+		Stderr: &stderr,
+		Getwd: func() (string, error) {
+			return dir, nil
+		},
+		Getenv: nopGetenv,
+	}).Run([]string{
+		"-o", filepath.Join(dir, "README.md"),
+		"-depfile", filepath.Join(dir, "build", "README.md.d"),
+		"-preface", filepath.Join(dir, "doc", "preface.txt"),
+		filepath.Join(dir, "doc", "SUMMARY.md"),
+	})
+	require.Equal(t, 0, exitCode, "stderr: %s", stderr.String())
+
+	got, err := os.ReadFile(filepath.Join(dir, "build", "README.md.d"))
+	require.NoError(t, err)
+
+	var want bytes.Buffer
+	require.NoError(t, writeDepfile(&want, filepath.Join(dir, "README.md"), []string{
+		filepath.Join(dir, "doc", "SUMMARY.md"),
+		filepath.Join(dir, "doc", "preface.txt"),
+		filepath.Join(dir, "doc", "foo.md"),
+		filepath.Join(dir, "doc", "bar", "SUMMARY.md"),
+		filepath.Join(dir, "doc", "bar", "baz.md"),
+	}))
+	assert.Equal(t, want.String(), string(got))
+}
+
+func TestMain_strict(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "summary.md"), []byte("- [foo](foo.md)\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "foo.md"), []byte("# Foo\n\nSee [bar](bar.md).\n"), 0o644))
+
+	run := func(args ...string) (exitCode int, stderr string) {
+		var stderrBuf bytes.Buffer
+		exitCode = (&mainCmd{
+			Stdin:  bytes.NewReader(nil),
+			Stdout: io.Discard,
+			Stderr: &stderrBuf,
+			Getwd: func() (string, error) {
+				return dir, nil
+			},
+			Getenv: nopGetenv,
+		}).Run(append(args, "-o", filepath.Join(dir, "out.md"), filepath.Join(dir, "summary.md")))
+		return exitCode, stderrBuf.String()
+	}
+
+	t.Run("warning", func(t *testing.T) {
+		exitCode, stderr := run()
+		assert.Equal(t, _exitOK, exitCode)
+		assert.Equal(t,
+			`warning: foo.md:3:6:broken link "bar.md": file not found`+"\n"+
+				"  3 | See [bar](bar.md).\n"+
+				"    |      ^\n", stderr)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		exitCode, stderr := run("-strict")
+		assert.Equal(t, _exitError, exitCode)
+		assert.Equal(t,
+			`foo.md:3:6:broken link "bar.md": file not found`+"\n"+
+				"  3 | See [bar](bar.md).\n"+
+				"    |      ^\n"+
+				"stitchmd: found 1 broken link(s)\n", stderr)
+	})
+}
+
+func TestMain_strictFrontMatter(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "summary.md"), []byte("- [foo](foo.md)\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "foo.md"), []byte("---\ntitel: Foo\n---\n\n# Foo\n"), 0o644))
+
+	var stderr bytes.Buffer
+	exitCode := (&mainCmd{
+		Stdin:  bytes.NewReader(nil),
+		Stdout: io.Discard,
+		Stderr: &stderr,
+		Getwd: func() (string, error) {
+			return dir, nil
+		},
+		Getenv: nopGetenv,
+	}).Run([]string{"-strict", "-o", filepath.Join(dir, "out.md"), filepath.Join(dir, "summary.md")})
+	assert.Equal(t, _exitError, exitCode)
+	assert.Equal(t,
+		`foo.md:2:1:unknown front matter key "titel"`+"\n"+
+			"  2 | titel: Foo\n"+
+			"    | ^\n"+
+			"stitchmd: found 1 problem(s) in front matter\n", stderr.String())
+}
+
+func TestMain_diagnosticsJSON(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	docs := filepath.Join(dir, "doc")
+	require.NoError(t, os.MkdirAll(docs, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(docs, "summary.md"),
+		[]byte("- [foo](foo.md)\n- [bar](bar.md)\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(docs, "foo.md"),
+		[]byte("# Foo\n\nHello, {{ .Name }}.\n"), 0o644))
+
+	var stderr bytes.Buffer
+	exitCode := (&mainCmd{
+		Stdin:  bytes.NewReader(nil),
+		Stdout: io.Discard,
+		Stderr: &stderr,
+		Getwd: func() (string, error) {
+			return dir, nil
+		},
+		Getenv: nopGetenv,
+	}).Run([]string{"-diagnostics", "json", "-o", filepath.Join(dir, "out.md"), filepath.Join(docs, "summary.md")})
+	assert.Equal(t, _exitError, exitCode)
+
+	var got []diagnostic
+	dec := json.NewDecoder(&stderr)
+	for dec.More() {
+		var d diagnostic
+		require.NoError(t, dec.Decode(&d))
+		got = append(got, d)
+	}
+
+	// Paths are relative to the current directory,
+	// not the directory of the summary.
+	require.Len(t, got, 3)
+	assert.Equal(t, diagnostic{
+		File:     filepath.Join("doc", "foo.md"),
+		Line:     3,
+		Column:   8,
+		Severity: "error",
+		Code:     "input",
+		Message:  `undefined variable "Name"`,
+	}, got[0], "error in included file")
+	assert.Equal(t, filepath.Join("doc", "summary.md"), got[1].File)
+	assert.Equal(t, 2, got[1].Line)
+	assert.Contains(t, got[1].Message, "bar.md")
+	assert.Equal(t, diagnostic{
+		Severity: "error",
+		Message:  "error reading markdown",
+	}, got[2])
+}
+
+func TestMain_sarif(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	docs := filepath.Join(dir, "doc")
+	require.NoError(t, os.MkdirAll(docs, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(docs, "summary.md"), []byte("- [foo](foo.md)\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(docs, "foo.md"), []byte("# Foo\n\nSee [bar](bar.md).\n"), 0o644))
+
+	sarifPath := filepath.Join(dir, "out.sarif")
+	exitCode := (&mainCmd{
+		Stdin:  bytes.NewReader(nil),
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+		Getwd: func() (string, error) {
+			return dir, nil
+		},
+		Getenv: nopGetenv,
+	}).Run([]string{
+		"-strict", "-sarif", sarifPath,
+		"-o", filepath.Join(dir, "README.md"),
+		filepath.Join(docs, "summary.md"),
+	})
+	assert.Equal(t, _exitError, exitCode)
+
+	data, err := os.ReadFile(sarifPath)
+	require.NoError(t, err, "report must be written even if the run fails")
+
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(data, &log))
+	require.Len(t, log.Runs, 1)
+	assert.Equal(t, []sarifResult{
+		{
+			RuleID:  "broken-link",
+			Level:   "error",
+			Message: sarifMessage{Text: `broken link "bar.md": file not found`},
+			Locations: []sarifLocation{
+				{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: "doc/foo.md"},
+						Region:           &sarifRegion{StartLine: 3, StartColumn: 6},
+					},
+				},
+			},
+		},
+	}, log.Runs[0].Results)
+}
+
+func TestMain_sarifSummaryFrontMatter(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	docs := filepath.Join(dir, "doc")
+	require.NoError(t, os.MkdirAll(docs, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(docs, "summary.md"),
+		[]byte("---\nbogus: 1\n---\n\n- [foo](foo.md)\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(docs, "foo.md"), []byte("# Foo\n"), 0o644))
+
+	sarifPath := filepath.Join(dir, "out.sarif")
+	exitCode := (&mainCmd{
+		Stdin:  bytes.NewReader(nil),
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+		Getwd: func() (string, error) {
+			return dir, nil
+		},
+		Getenv: nopGetenv,
+	}).Run([]string{
+		"-strict", "-sarif", sarifPath,
+		filepath.Join(docs, "summary.md"),
+	})
+	assert.Equal(t, _exitError, exitCode)
+
+	data, err := os.ReadFile(sarifPath)
+	require.NoError(t, err, "report must be written for problems in the summary")
+
+	// The count of problems has no location,
+	// so it isn't part of the report.
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(data, &log))
+	require.Len(t, log.Runs, 1)
+	assert.Equal(t, []sarifResult{
+		{
+			RuleID:  "front-matter",
+			Level:   "error",
+			Message: sarifMessage{Text: `unknown front matter key "bogus"`},
+			Locations: []sarifLocation{
+				{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: "doc/summary.md"},
+						Region:           &sarifRegion{StartLine: 2, StartColumn: 1},
+					},
+				},
+			},
+		},
+	}, log.Runs[0].Results)
+}
+
 func TestDiffWriter(t *testing.T) {
 	t.Parallel()
 
@@ -281,6 +626,8 @@ func TestDiffWriter(t *testing.T) {
 		_, err = io.WriteString(w, "hello world")
 		assert.NoError(t, err)
 
+		assert.True(t, w.Changed())
+
 		var buf bytes.Buffer
 		assert.NoError(t, w.Diff(&buf))
 		assert.Contains(t, buf.String(), "+hello world")
@@ -299,6 +646,7 @@ func TestDiffWriter(t *testing.T) {
 
 		_, err = io.WriteString(w, "hello\nbar")
 		require.NoError(t, err)
+		assert.True(t, w.Changed())
 
 		var buf bytes.Buffer
 		assert.NoError(t, w.Diff(&buf))
@@ -319,6 +667,7 @@ func TestDiffWriter(t *testing.T) {
 
 		_, err = io.WriteString(w, "hello world")
 		require.NoError(t, err)
+		assert.False(t, w.Changed())
 
 		var buf bytes.Buffer
 		assert.NoError(t, w.Diff(&buf))
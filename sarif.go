@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"go.abhg.dev/stitchmd/internal/errdefer"
+	"go.abhg.dev/stitchmd/stitchmd"
+)
+
+// _sarifRules describes the kinds of problems
+// that may be reported in a SARIF log.
+var _sarifRules = []sarifRule{
+	{
+		ID:               stitchmd.CodeSummary,
+		ShortDescription: sarifMessage{Text: "The summary file is invalid."},
+	},
+	{
+		ID:               stitchmd.CodeInput,
+		ShortDescription: sarifMessage{Text: "A file referenced by the summary could not be read or is invalid."},
+	},
+	{
+		ID:               stitchmd.CodeFrontMatter,
+		ShortDescription: sarifMessage{Text: "Front matter has an unknown key."},
+	},
+	{
+		ID:               stitchmd.CodeBrokenLink,
+		ShortDescription: sarifMessage{Text: "A link points to a file or heading that does not exist."},
+	},
+}
+
+// writeSARIFTo writes a SARIF log with the given diagnostics
+// to the given path.
+//
+// File paths in the diagnostics are reported relative to cwd.
+func writeSARIFTo(path, cwd string, diags []diagnostic) (err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer errdefer.Closef(&err, f, "close %q", path)
+
+	return writeSARIF(f, cwd, diags)
+}
+
+// writeSARIF writes a SARIF 2.1.0 log with the given diagnostics.
+//
+// File paths in the diagnostics are reported relative to cwd.
+func writeSARIF(w io.Writer, cwd string, diags []diagnostic) error {
+	results := make([]sarifResult, 0, len(diags))
+	for _, d := range diags {
+		msg := d.Message
+		if d.Hint != "" {
+			msg += "; " + d.Hint
+		}
+		result := sarifResult{
+			RuleID:  d.Code,
+			Level:   d.Severity,
+			Message: sarifMessage{Text: msg},
+		}
+		if d.File != "" {
+			loc := sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: sarifURI(cwd, d.File)},
+			}
+			if d.Line > 0 {
+				loc.Region = &sarifRegion{
+					StartLine:   d.Line,
+					StartColumn: sarifColumn(d.source, d.Column),
+				}
+			}
+			result.Locations = []sarifLocation{{PhysicalLocation: loc}}
+		}
+		results = append(results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: sarifDriver{
+						Name:           "stitchmd",
+						Version:        strings.TrimSpace(_version),
+						InformationURI: "https://github.com/abhinav/stitchmd",
+						Rules:          _sarifRules,
+					},
+				},
+				// See sarifColumn.
+				ColumnKind: "unicodeCodePoints",
+				Results:    results,
+			},
+		},
+	})
+}
+
+// sarifColumn converts a 1-indexed byte column in the given line
+// to the 1-indexed code point column reported in SARIF logs.
+//
+// It returns 0, leaving the column out of the log,
+// if the line isn't known or the column isn't inside it.
+func sarifColumn(line string, column int) int {
+	if column < 1 || column-1 > len(line) {
+		return 0
+	}
+	return utf8.RuneCountInString(line[:column-1]) + 1
+}
+
+// sarifURI returns the URI of a file for a SARIF log.
+// Relative paths are left as-is,
+// and absolute paths are made relative to cwd if possible.
+func sarifURI(cwd, file string) string {
+	if filepath.IsAbs(file) {
+		if rel, err := filepath.Rel(cwd, file); err == nil {
+			file = rel
+		}
+	}
+
+	u := url.URL{Path: filepath.ToSlash(file)}
+	if filepath.IsAbs(file) {
+		u.Scheme = "file"
+	}
+	return u.String()
+}
+
+// The types below define the parts of the SARIF 2.1.0 format
+// used by stitchmd.
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId,omitempty"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
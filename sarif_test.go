@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, writeSARIF(&buf, "/src", []diagnostic{
+		{
+			File:     "/src/doc/intro.md",
+			Line:     5,
+			Column:   6,
+			Severity: "warning",
+			Code:     "broken-link",
+			Message:  `broken link "install.md": file not found`,
+			source:   "See [install](install.md).",
+		},
+		{
+			File:     "/src/doc/intro.md",
+			Line:     7,
+			Column:   9,
+			Severity: "warning",
+			Code:     "broken-link",
+			Message:  `broken link "upgrade.md": file not found`,
+			source:   "Voilà, [upgrade](upgrade.md).",
+		},
+		{
+			File:     "/src/doc/intro.md",
+			Line:     9,
+			Column:   3,
+			Severity: "warning",
+			Code:     "broken-link",
+			Message:  `broken link "faq.md": file not found`,
+		},
+		{
+			Severity: "error",
+			Message:  "great sadness",
+		},
+	}))
+
+	assert.JSONEq(t, `{
+		"version": "2.1.0",
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"runs": [{
+			"tool": {"driver": {
+				"name": "stitchmd",
+				"version": "dev",
+				"informationUri": "https://github.com/abhinav/stitchmd",
+				"rules": [
+					{"id": "summary", "shortDescription": {"text": "The summary file is invalid."}},
+					{"id": "input", "shortDescription": {"text": "A file referenced by the summary could not be read or is invalid."}},
+					{"id": "front-matter", "shortDescription": {"text": "Front matter has an unknown key."}},
+					{"id": "broken-link", "shortDescription": {"text": "A link points to a file or heading that does not exist."}}
+				]
+			}},
+			"columnKind": "unicodeCodePoints",
+			"results": [
+				{
+					"ruleId": "broken-link",
+					"level": "warning",
+					"message": {"text": "broken link \"install.md\": file not found"},
+					"locations": [{"physicalLocation": {
+						"artifactLocation": {"uri": "doc/intro.md"},
+						"region": {"startLine": 5, "startColumn": 6}
+					}}]
+				},
+				{
+					"ruleId": "broken-link",
+					"level": "warning",
+					"message": {"text": "broken link \"upgrade.md\": file not found"},
+					"locations": [{"physicalLocation": {
+						"artifactLocation": {"uri": "doc/intro.md"},
+						"region": {"startLine": 7, "startColumn": 8}
+					}}]
+				},
+				{
+					"ruleId": "broken-link",
+					"level": "warning",
+					"message": {"text": "broken link \"faq.md\": file not found"},
+					"locations": [{"physicalLocation": {
+						"artifactLocation": {"uri": "doc/intro.md"},
+						"region": {"startLine": 9}
+					}}]
+				},
+				{
+					"level": "error",
+					"message": {"text": "great sadness"}
+				}
+			]
+		}]
+	}`, buf.String())
+}
+
+func TestSARIFColumn(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc   string
+		line   string
+		column int
+		want   int
+	}{
+		{desc: "ascii", line: "See [foo](foo.md).", column: 5, want: 5},
+		{desc: "after multi-byte", line: "Voilà, [foo](foo.md).", column: 9, want: 8},
+		{desc: "before multi-byte", line: "[foo](foo.md) voilà", column: 1, want: 1},
+		{desc: "end of line", line: "日本", column: 7, want: 3},
+		{desc: "unknown line", line: "", column: 5, want: 0},
+		{desc: "past end of line", line: "foo", column: 10, want: 0},
+		{desc: "no column", line: "foo", column: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, sarifColumn(tt.line, tt.column))
+		})
+	}
+}
+
+func TestSARIFURI(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc string
+		cwd  string
+		file string
+		want string
+	}{
+		{desc: "relative", cwd: "/src", file: "doc/intro.md", want: "doc/intro.md"},
+		{desc: "absolute", cwd: "/src", file: "/src/doc/intro.md", want: "doc/intro.md"},
+		{desc: "outside cwd", cwd: "/src", file: "/other/intro.md", want: "../other/intro.md"},
+		{desc: "escaped", cwd: "/src", file: "my doc.md", want: "my%20doc.md"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got := sarifURI(filepath.FromSlash(tt.cwd), filepath.FromSlash(tt.file))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package stitchmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// assetBundler copies files referenced from Markdown files
+// into a single assets directory.
+//
+// Files with identical contents are copied only once.
+// Files with the same name but different contents
+// are disambiguated with a hash of their contents.
+type assetBundler struct {
+	// FS holds the input files.
+	FS fs.FS // required
+
+	// URLPath is the /-separated path to the assets directory
+	// from the output directory.
+	URLPath string // required
+
+	byPath map[string]*asset // source path => asset
+	byHash map[string]*asset // content hash => asset
+	byName map[string]*asset // file name => asset
+	assets []*asset
+}
+
+// asset is a single file in the assets directory.
+type asset struct {
+	Name string // name in the assets directory
+	Src  string // /-separated path in FS
+	Data []byte
+}
+
+// Add adds the file at the given /-separated path in FS to the bundle,
+// and returns the path to it relative to the output directory.
+//
+// It returns false if the file can't be bundled,
+// for example because it doesn't exist or is a directory.
+func (b *assetBundler) Add(p string) (string, bool) {
+	if b.byPath == nil {
+		b.byPath = make(map[string]*asset)
+		b.byHash = make(map[string]*asset)
+		b.byName = make(map[string]*asset)
+	}
+
+	if a, ok := b.byPath[p]; ok {
+		return b.url(a), true
+	}
+
+	data, err := fs.ReadFile(b.FS, p)
+	if err != nil {
+		return "", false
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if a, ok := b.byHash[hash]; ok {
+		b.byPath[p] = a
+		return b.url(a), true
+	}
+
+	name := path.Base(p)
+	if _, ok := b.byName[name]; ok {
+		ext := path.Ext(name)
+		name = fmt.Sprintf("%v-%v%v", strings.TrimSuffix(name, ext), hash[:8], ext)
+	}
+
+	a := &asset{Name: name, Src: p, Data: data}
+	b.byPath[p] = a
+	b.byHash[hash] = a
+	b.byName[name] = a
+	b.assets = append(b.assets, a)
+	return b.url(a), true
+}
+
+func (b *assetBundler) url(a *asset) string {
+	return path.Join(b.URLPath, a.Name)
+}
+
+// Sources reports the /-separated paths in FS of all bundled files.
+func (b *assetBundler) Sources() []string {
+	srcs := make([]string, len(b.assets))
+	for i, a := range b.assets {
+		srcs[i] = a.Src
+	}
+	return srcs
+}
+
+// WriteTo writes all bundled files into the given directory,
+// creating it if necessary.
+func (b *assetBundler) WriteTo(dir string) error {
+	if len(b.assets) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, a := range b.assets {
+		if err := os.WriteFile(filepath.Join(dir, a.Name), a.Data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package stitchmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssetBundler(t *testing.T) {
+	t.Parallel()
+
+	b := assetBundler{
+		FS: fstest.MapFS{
+			"a/logo.png":      {Data: []byte("logo")},
+			"b/logo.png":      {Data: []byte("logo")},
+			"b/copy.png":      {Data: []byte("logo")},
+			"c/logo.png":      {Data: []byte("other logo")},
+			"c/sub/index.txt": {Data: []byte("index")},
+		},
+		URLPath: "../assets",
+	}
+
+	add := func(p string) string {
+		t.Helper()
+
+		got, ok := b.Add(p)
+		require.True(t, ok, "add %v", p)
+		return got
+	}
+
+	assert.Equal(t, "../assets/logo.png", add("a/logo.png"))
+	assert.Equal(t, "../assets/logo.png", add("a/logo.png"), "same file")
+	assert.Equal(t, "../assets/logo.png", add("b/logo.png"), "same contents")
+	assert.Equal(t, "../assets/logo.png", add("b/copy.png"), "same contents, different name")
+	assert.Equal(t, "../assets/logo-419cc99c.png", add("c/logo.png"), "name conflict")
+
+	_, ok := b.Add("does/not/exist.png")
+	assert.False(t, ok, "missing file")
+	_, ok = b.Add("c/sub")
+	assert.False(t, ok, "directory")
+
+	assert.Equal(t, []string{"a/logo.png", "c/logo.png"}, b.Sources())
+
+	dir := filepath.Join(t.TempDir(), "assets")
+	require.NoError(t, b.WriteTo(dir))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"logo-419cc99c.png", "logo.png"}, names)
+
+	got, err := os.ReadFile(filepath.Join(dir, "logo-419cc99c.png"))
+	require.NoError(t, err)
+	assert.Equal(t, "other logo", string(got))
+}
+
+func TestAssetBundler_empty(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "assets")
+	require.NoError(t, (&assetBundler{FS: fstest.MapFS{}}).WriteTo(dir))
+
+	_, err := os.Stat(dir)
+	assert.ErrorIs(t, err, os.ErrNotExist, "directory should not be created")
+}
@@ -0,0 +1,295 @@
+package stitchmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+	"go.abhg.dev/stitchmd/internal/goldast"
+)
+
+// Fenced code blocks in included Markdown files
+// can pull their contents from another file:
+//
+//	```go include=example_test.go region=setup
+//	```
+//
+// The block's contents are replaced with the contents of that file,
+// optionally limited to a range of lines or a named region.
+
+// codeInclude is a parsed include directive
+// from the info string of a fenced code block.
+type codeInclude struct {
+	// Path to the file, relative to the Markdown file.
+	Path string
+
+	// Range of lines to include, 1-indexed and inclusive.
+	// Zero values mean the start and end of the file.
+	Start, End int
+
+	// Name of the region to include, if any.
+	Region string
+
+	// Info string of the code block without the include directive.
+	Info string
+}
+
+// parseCodeInclude parses the info string of a fenced code block.
+// It returns nil if the info string doesn't have an include directive.
+func parseCodeInclude(info string) (*codeInclude, error) {
+	var (
+		inc   codeInclude
+		lines string
+		rest  []string
+	)
+	for _, field := range strings.Fields(info) {
+		key, value, ok := strings.Cut(field, "=")
+		switch {
+		case ok && key == "include":
+			inc.Path = value
+		case ok && key == "lines":
+			lines = value
+		case ok && key == "region":
+			inc.Region = value
+		default:
+			rest = append(rest, field)
+		}
+	}
+	inc.Info = strings.Join(rest, " ")
+
+	// Leave other info strings alone,
+	// even if they happen to use the same keys.
+	if inc.Path == "" {
+		return nil, nil
+	}
+
+	if lines != "" {
+		if inc.Region != "" {
+			return nil, errors.New("cannot use both lines and region")
+		}
+
+		var err error
+		inc.Start, inc.End, err = parseLineRange(lines)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &inc, nil
+}
+
+// parseLineRange parses a line range in one of the following forms:
+//
+//	N    just line N
+//	N-M  lines N through M
+//	N-   line N through the end of the file
+//	-M   start of the file through line M
+func parseLineRange(s string) (start, end int, err error) {
+	startStr, endStr, isRange := strings.Cut(s, "-")
+	if !isRange {
+		endStr = startStr
+	}
+
+	if startStr != "" {
+		start, err = strconv.Atoi(startStr)
+		if err != nil || start < 1 {
+			return 0, 0, fmt.Errorf("bad line range %q", s)
+		}
+	}
+	if endStr != "" {
+		end, err = strconv.Atoi(endStr)
+		if err != nil || end < 1 {
+			return 0, 0, fmt.Errorf("bad line range %q", s)
+		}
+	}
+	if start > 0 && end > 0 && start > end {
+		return 0, 0, fmt.Errorf("bad line range %q: start is after end", s)
+	}
+	return start, end, nil
+}
+
+// Region markers are comments in the form:
+//
+//	// region name
+//	...
+//	// endregion
+//
+// Comments may start with any of //, #, <!--, --, /*, or ;
+// so these work with most languages.
+// Only lines that start with a comment are markers
+// so that code using the word "region" isn't mistaken for one.
+var (
+	_regionStart = regexp.MustCompile(`^\s*(?://+|#+|<!--|--|/\*+|;+)\s*region\s+(\S+)`)
+	_regionEnd   = regexp.MustCompile(`^\s*(?://+|#+|<!--|--|/\*+|;+)\s*endregion\b`)
+)
+
+// extract returns the requested part of the given file contents.
+func (inc *codeInclude) extract(src []byte) ([]byte, error) {
+	lines := strings.SplitAfter(string(src), "\n")
+	if n := len(lines); n > 0 && lines[n-1] == "" {
+		lines = lines[:n-1]
+	}
+
+	switch {
+	case inc.Region != "":
+		start := -1
+		for i, line := range lines {
+			if m := _regionStart.FindStringSubmatch(line); m != nil && m[1] == inc.Region {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return nil, fmt.Errorf("region %q not found", inc.Region)
+		}
+
+		// Regions may be nested,
+		// so skip over the ends of regions opened inside this one.
+		end, depth := -1, 0
+		for i := start; i < len(lines) && end < 0; i++ {
+			switch {
+			case _regionStart.MatchString(lines[i]):
+				depth++
+			case _regionEnd.MatchString(lines[i]):
+				if depth == 0 {
+					end = i
+				}
+				depth--
+			}
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("region %q is never closed", inc.Region)
+		}
+		lines = lines[start:end]
+
+		// Drop markers of regions nested inside this one.
+		kept := lines[:0:0]
+		for _, line := range lines {
+			if !_regionStart.MatchString(line) && !_regionEnd.MatchString(line) {
+				kept = append(kept, line)
+			}
+		}
+		lines = dedentLines(kept)
+
+	case inc.Start > 0 || inc.End > 0:
+		start, end := max(inc.Start, 1), inc.End
+		if end == 0 {
+			end = len(lines)
+		}
+		if end > len(lines) {
+			return nil, fmt.Errorf("line %d is out of range: file has %d lines", end, len(lines))
+		}
+		lines = dedentLines(lines[start-1 : end])
+	}
+
+	out := []byte(strings.Join(lines, ""))
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+	return out, nil
+}
+
+// dedentLines removes leading whitespace common to all non-blank lines.
+func dedentLines(lines []string) []string {
+	var prefix string
+	first := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if prefix == "" {
+		return lines
+	}
+
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = strings.TrimPrefix(line, prefix)
+		if strings.TrimSpace(line) == "" {
+			out[i] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return out
+}
+
+// includeCode resolves include directives in fenced code blocks
+// in the given Markdown file.
+//
+// The included contents are appended to the file's source,
+// and the code blocks are updated to point to them.
+func (c *collector) includeCode(f *goldast.File, filePath string) error {
+	var blocks []*ast.FencedCodeBlock
+	_ = goldast.Walk(f.AST, func(n ast.Node) error {
+		if b, ok := n.(*ast.FencedCodeBlock); ok && b.Info != nil {
+			blocks = append(blocks, b)
+		}
+		return nil
+	})
+
+	// Report errors at the info string
+	// because the block may not have any lines.
+	errs := goldast.NewInlineErrorList(f.Info)
+	for _, b := range blocks {
+		inc, err := parseCodeInclude(string(b.Info.Segment.Value(f.Source)))
+		if err != nil {
+			errs.Pushf(b.Info, "%v", err)
+			continue
+		}
+		if inc == nil {
+			continue
+		}
+
+		data, err := c.readFile(path.Join(path.Dir(filePath), inc.Path))
+		if err != nil {
+			errs.Pushf(b.Info, "include %v: %v", inc.Path, err)
+			continue
+		}
+
+		code, err := inc.extract(data)
+		if err != nil {
+			errs.Pushf(b.Info, "include %v: %v", inc.Path, err)
+			continue
+		}
+
+		var src bytes.Buffer
+		src.Write(f.Source)
+
+		b.Info = nil
+		if inc.Info != "" {
+			start := src.Len()
+			src.WriteString(inc.Info)
+			b.Info = ast.NewTextSegment(text.NewSegment(start, src.Len()))
+		}
+
+		lines := text.NewSegments()
+		for len(code) > 0 {
+			line := code
+			if idx := bytes.IndexByte(code, '\n'); idx >= 0 {
+				line = code[:idx+1]
+			}
+			start := src.Len()
+			src.Write(line)
+			lines.Append(text.NewSegment(start, src.Len()))
+			code = code[len(line):]
+		}
+		b.SetLines(lines)
+
+		f.Source = src.Bytes()
+	}
+
+	return errs.Err()
+}
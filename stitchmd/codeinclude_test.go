@@ -0,0 +1,265 @@
+package stitchmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCodeInclude(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc string
+		give string
+		want *codeInclude
+	}{
+		{desc: "no directive", give: "go"},
+		{desc: "empty", give: ""},
+		{desc: "lines without include", give: "go lines=1-2"},
+		{
+			desc: "file",
+			give: "go include=foo.go",
+			want: &codeInclude{Path: "foo.go", Info: "go"},
+		},
+		{
+			desc: "no language",
+			give: "include=foo.go",
+			want: &codeInclude{Path: "foo.go"},
+		},
+		{
+			desc: "lines",
+			give: "go include=foo.go lines=3-5 {.extra}",
+			want: &codeInclude{Path: "foo.go", Start: 3, End: 5, Info: "go {.extra}"},
+		},
+		{
+			desc: "region",
+			give: "go region=setup include=../foo.go",
+			want: &codeInclude{Path: "../foo.go", Region: "setup", Info: "go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseCodeInclude(tt.give)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseCodeInclude_errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc    string
+		give    string
+		wantErr string
+	}{
+		{
+			desc:    "lines and region",
+			give:    "go include=foo.go lines=1 region=foo",
+			wantErr: "cannot use both lines and region",
+		},
+		{
+			desc:    "bad lines",
+			give:    "go include=foo.go lines=a-b",
+			wantErr: `bad line range "a-b"`,
+		},
+		{
+			desc:    "zero line",
+			give:    "go include=foo.go lines=0",
+			wantErr: `bad line range "0"`,
+		},
+		{
+			desc:    "backwards",
+			give:    "go include=foo.go lines=5-3",
+			wantErr: `bad line range "5-3": start is after end`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseCodeInclude(tt.give)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestParseLineRange(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		give       string
+		start, end int
+	}{
+		{give: "3", start: 3, end: 3},
+		{give: "3-5", start: 3, end: 5},
+		{give: "3-", start: 3},
+		{give: "-5", end: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.give, func(t *testing.T) {
+			t.Parallel()
+
+			start, end, err := parseLineRange(tt.give)
+			require.NoError(t, err)
+			assert.Equal(t, tt.start, start)
+			assert.Equal(t, tt.end, end)
+		})
+	}
+}
+
+func TestCodeIncludeExtract(t *testing.T) {
+	t.Parallel()
+
+	const src = "package foo\n" +
+		"\n" +
+		"func Foo() {\n" +
+		"\t// region setup\n" +
+		"\tx := 1\n" +
+		"\t// region inner\n" +
+		"\tif x > 0 {\n" +
+		"\t\tx++\n" +
+		"\t}\n" +
+		"\t// endregion\n" +
+		"\tx *= 2\n" +
+		"\t// endregion\n" +
+		"}"
+
+	tests := []struct {
+		desc    string
+		give    codeInclude
+		want    string
+		wantErr string
+	}{
+		{
+			desc: "whole file",
+			want: src + "\n",
+		},
+		{
+			desc: "lines",
+			give: codeInclude{Start: 7, End: 9},
+			want: "if x > 0 {\n\tx++\n}\n",
+		},
+		{
+			desc: "lines to end",
+			give: codeInclude{Start: 13},
+			want: "}\n",
+		},
+		{
+			desc: "lines from start",
+			give: codeInclude{End: 1},
+			want: "package foo\n",
+		},
+		{
+			desc:    "lines out of range",
+			give:    codeInclude{Start: 10, End: 20},
+			wantErr: "line 20 is out of range: file has 13 lines",
+		},
+		{
+			desc: "region",
+			give: codeInclude{Region: "setup"},
+			want: "x := 1\nif x > 0 {\n\tx++\n}\nx *= 2\n",
+		},
+		{
+			desc: "nested region",
+			give: codeInclude{Region: "inner"},
+			want: "if x > 0 {\n\tx++\n}\n",
+		},
+		{
+			desc:    "unknown region",
+			give:    codeInclude{Region: "nope"},
+			wantErr: `region "nope" not found`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.give.extract([]byte(src))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestCodeIncludeExtract_regionInCode(t *testing.T) {
+	t.Parallel()
+
+	const src = "// region foo\n" +
+		"region := 1\n" +
+		"return region + 1\n" +
+		"// endregion\n"
+
+	got, err := (&codeInclude{Region: "foo"}).extract([]byte(src))
+	require.NoError(t, err)
+	assert.Equal(t, "region := 1\nreturn region + 1\n", string(got))
+}
+
+func TestCodeIncludeExtract_unclosedRegion(t *testing.T) {
+	t.Parallel()
+
+	_, err := (&codeInclude{Region: "foo"}).extract([]byte("# region foo\nbar\n"))
+	assert.ErrorContains(t, err, `region "foo" is never closed`)
+}
+
+func TestRegionMarkers(t *testing.T) {
+	t.Parallel()
+
+	starts := []string{
+		"// region foo",
+		"\t// region foo",
+		"# region foo",
+		"#region foo",
+		"-- region foo",
+		"<!-- region foo -->",
+		"/* region foo */",
+		";; region foo",
+	}
+	for _, line := range starts {
+		m := _regionStart.FindStringSubmatch(line)
+		if assert.NotNil(t, m, "%q", line) {
+			assert.Equal(t, "foo", m[1], "%q", line)
+		}
+	}
+
+	ends := []string{
+		"// endregion",
+		"\t// endregion foo",
+		"#endregion",
+		"<!-- endregion -->",
+		"/* endregion */",
+		"; endregion",
+	}
+	for _, line := range ends {
+		assert.True(t, _regionEnd.MatchString(line), "%q", line)
+		assert.False(t, _regionStart.MatchString(line), "%q", line)
+	}
+
+	// Code that happens to use the word "region" isn't a marker.
+	code := []string{
+		"x := region(foo)",
+		"foo region bar",
+		"return region + 1",
+		"region := newRegion()",
+		"\tendregion()",
+		"x = y # region foo",
+		"call(); // endregion",
+	}
+	for _, line := range code {
+		assert.False(t, _regionStart.MatchString(line), "%q", line)
+		assert.False(t, _regionEnd.MatchString(line), "%q", line)
+	}
+}
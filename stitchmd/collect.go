@@ -0,0 +1,787 @@
+package stitchmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"go.abhg.dev/goldmark/toc"
+	"go.abhg.dev/stitchmd/internal/goldast"
+	"go.abhg.dev/stitchmd/internal/header"
+	"go.abhg.dev/stitchmd/internal/rawhtml"
+	"go.abhg.dev/stitchmd/internal/stitch"
+	"go.abhg.dev/stitchmd/internal/tree"
+)
+
+// collector loads all Markdown files in a TOC
+// and builds an alternative, parsed representation of the files.
+type collector struct {
+	Parser parser.Parser // required
+	FS     fs.FS         // required
+
+	// Paths relative to root of fs.FS,
+	// representing the stack of summary file embeds.
+	// Used to detect cycles.
+	Stack []string
+
+	// Paths relative to root of fs.FS
+	// that glob items in the summary never match.
+	// This is used to keep the output file out of the output.
+	GlobIgnore []string
+
+	// Directory under FS to resolve relative paths from.
+	// Must use '/' as the path separator.
+	Dir string
+
+	// Slugger generates IDs for headings.
+	// Defaults to header.GitHub.
+	Slugger header.Slugger
+
+	// Profile selects which items and blocks of content
+	// limited to certain profiles are included.
+	// Those limited to other profiles are left out.
+	Profile string
+
+	// Vars holds values of variables
+	// that may be referenced from included files.
+	Vars map[string]string
+
+	idGen    *header.IDGen
+	inputs   *pathSet
+	warnings *[]error
+	files    map[string]*markdownFileItem
+
+	// Files parsed before collection
+	// to decide whether to include them.
+	// See peekFile.
+	parsed map[string]*parsedFile
+}
+
+type markdownCollection struct {
+	Sections []*markdownSection
+
+	// FilesByPath maps a Markdown file path to its parsed representation.
+	// The path is /-separated, regardless of the OS.
+	FilesByPath map[string]*markdownFileItem
+
+	// Inputs lists the paths of all files that the collector
+	// attempted to read, including those read for nested summaries,
+	// in the order they were first read.
+	//
+	// Paths are /-separated and relative to the root of the FS.
+	Inputs []string
+
+	// Warnings lists problems found in the files
+	// that don't prevent them from being included,
+	// including those found in nested summaries.
+	Warnings []error
+
+	// Dir is the directory under the FS
+	// that paths in FilesByPath are relative to.
+	Dir string
+}
+
+func (c *collector) Collect(info goldast.Positioner, toc *stitch.Summary) (*markdownCollection, error) {
+	c.files = make(map[string]*markdownFileItem)
+	if c.Slugger == nil {
+		c.Slugger = header.GitHub
+	}
+	if c.idGen == nil {
+		c.idGen = header.NewIDGen(c.Slugger)
+	}
+	if c.inputs == nil {
+		c.inputs = new(pathSet)
+	}
+	if c.warnings == nil {
+		c.warnings = new([]error)
+	}
+
+	errs := goldast.NewErrorList(info)
+	sections := make([]*markdownSection, len(toc.Sections))
+	for i, sec := range toc.Sections {
+		sections[i] = c.collectSection(errs, sec)
+	}
+
+	return &markdownCollection{
+		Sections:    sections,
+		FilesByPath: c.files,
+		Inputs:      c.inputs.Paths(),
+		Warnings:    slices.Clone(*c.warnings),
+		Dir:         c.Dir,
+	}, errs.Err()
+}
+
+type markdownSection struct {
+	Title    *ast.Heading
+	TOCItems *ast.List
+	Items    tree.List[markdownItem]
+}
+
+func (c *collector) collectSection(errs *goldast.ErrorList, sec *stitch.Section) *markdownSection {
+	items := tree.TransformList(c.expandGlobs(errs, c.dropSkipped(c.dropProfiles(sec.Items))), func(cursor tree.Cursor[stitch.Item]) markdownItem {
+		i, err := c.collectItem(cursor)
+		if err != nil {
+			// Wrap instead of formatting the error
+			// to keep the positions of errors in included files.
+			errs.Pushf(cursor.Value().Node(), "%w", err)
+			return nil
+		}
+		return i
+	})
+
+	var title *ast.Heading
+	if sec.Title != nil {
+		title = sec.Title.AST
+	}
+
+	return &markdownSection{
+		Title:    title,
+		TOCItems: sec.AST,
+		Items:    items,
+	}
+}
+
+// dropSkipped removes items for files that asked to be skipped,
+// or that aren't part of the collector's profile,
+// from the list and from the summary's TOC,
+// along with all items nested under them.
+// Links to skipped files will point to the original files.
+//
+// This must run before files are collected
+// so that headings of skipped files don't take up IDs
+// that the remaining headings would otherwise get.
+func (c *collector) dropSkipped(items tree.List[stitch.Item]) tree.List[stitch.Item] {
+	out := make(tree.List[stitch.Item], 0, len(items))
+	for _, n := range items {
+		if item, ok := n.Value.(*stitch.LinkItem); !ok || !c.isSkipped(item) {
+			n.List = c.dropSkipped(n.List)
+			out = append(out, n)
+			continue
+		}
+
+		removeTOCItem(n.Value.Node(), false /* keepChildren */)
+	}
+	return out
+}
+
+// isSkipped reports whether the front matter of the file
+// referenced by the given item leaves it out of the output.
+//
+// Problems reading the file are ignored here.
+// They're reported when the file is collected.
+func (c *collector) isSkipped(item *stitch.LinkItem) bool {
+	if u, err := url.Parse(item.Target); err == nil && u.Host != "" {
+		return false
+	}
+
+	filePath, _, _ := strings.Cut(item.Target, "#")
+	pf, err := c.peekFile(filePath)
+	if err != nil {
+		return false
+	}
+
+	skip := pf.Options.Skip || !stitch.MatchProfile(pf.Options.Profiles, c.Profile)
+	if skip {
+		// The file won't be collected.
+		delete(c.parsed, filePath)
+	}
+	return skip
+}
+
+// markdownItem unifies nodes of the following kinds:
+//
+//   - markdownFileItem: an included Markdown file
+//   - markdownGroupItem: a title without any files, grouping other items
+//   - markdownExternalLinkItem: an external link
+//   - markdownEmbedItem: a request to embed another summary file
+type markdownItem interface {
+	markdownItem()
+}
+
+func (c *collector) collectItem(cursor tree.Cursor[stitch.Item]) (markdownItem, error) {
+	item := cursor.Value()
+	switch item := item.(type) {
+	case *stitch.LinkItem:
+		return c.collectLinkItem(item, cursor)
+
+	case *stitch.EmbedItem:
+		return c.collectEmbedItem(item, cursor)
+
+	case *stitch.TextItem:
+		return c.collectGroupItem(item), nil
+
+	default:
+		panic(fmt.Sprintf("unhandled item type %T", item))
+	}
+}
+
+func (c *collector) collectLinkItem(item *stitch.LinkItem, cursor tree.Cursor[stitch.Item]) (markdownItem, error) {
+	u, err := url.Parse(item.Target)
+	if err == nil && u.Host != "" {
+		if cursor.ChildCount() > 0 {
+			return nil, errors.New("external link cannot have children")
+		}
+		return &markdownExternalLinkItem{
+			Item: item,
+		}, nil
+	}
+
+	return c.collectFileItem(item)
+}
+
+// markdownExternalLinkItem is a marker for external links
+// in the summary.
+type markdownExternalLinkItem struct {
+	Item *stitch.LinkItem
+}
+
+func (*markdownExternalLinkItem) markdownItem() {}
+
+type markdownFileItem struct {
+	// Path is the /-separated path to the Markdown file.
+	Path string
+
+	// Section is the ID of the heading in the Markdown file
+	// whose section was included,
+	// or empty if the whole file was included.
+	Section string
+
+	// Item is the original link in the TOC
+	// that referenced the Markdown file.
+	Item *stitch.LinkItem
+
+	// Title is the title of the Markdown file, if any.
+	Title *markdownHeading
+
+	// File is the parsed Markdown file
+	// that the link points to.
+	File *goldast.File
+
+	// Links holds all links that were found in the Markdown file.
+	Links []*ast.Link
+
+	// Images holds all images that were found in the Markdown file.
+	Images []*ast.Image
+
+	// Headings holds all headings that were found in the Markdown file.
+	Headings []*markdownHeading
+
+	// HeadingsByOldID maps IDs of headings, as interpreted in isolation.
+	// The IDs will change once interpreted as part of the combined document.
+	HeadingsByOldID map[string]*markdownHeading
+
+	HTMLPairs  rawhtml.Pairs
+	RawHTMLs   []*ast.RawHTML
+	HTMLBlocks []*ast.HTMLBlock
+
+	// Absorb indicates that the headings in this file
+	// should be included in the parent TOC.
+	Absorb bool
+	TOC    *toc.TOC
+
+	// NoTOC indicates that the file should not be listed
+	// in the parent TOC.
+	NoTOC bool
+}
+
+func (*markdownFileItem) markdownItem() {}
+
+func (c *collector) collectFileItem(item *stitch.LinkItem) (*markdownFileItem, error) {
+	// "foo.md#bar" includes only the section of foo.md under heading "bar".
+	filePath, section, _ := strings.Cut(item.Target, "#")
+	pf, err := c.takeFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	f, ctx, options := pf.File, pf.Context, pf.Options
+	*c.warnings = append(*c.warnings, pf.Warnings...)
+	if err := c.filterProfileBlocks(f); err != nil {
+		return nil, err
+	}
+
+	fidgen := header.NewIDGen(c.Slugger)
+	if section != "" {
+		if err := c.extractSection(f, fidgen, section); err != nil {
+			return nil, fmt.Errorf("%v: %w", filePath, err)
+		}
+	}
+
+	// Variables must be expanded before code is included
+	// so that included code is left as-is.
+	if err := c.expandVars(f, options.CodeVars); err != nil {
+		return nil, err
+	}
+	if err := c.includeCode(f, filePath); err != nil {
+		return nil, err
+	}
+
+	// The title is picked before heading IDs are generated
+	// so that a title from the front matter doesn't leave
+	// the ID of the title it replaces taken.
+	titleNode := titleHeading(f.AST, section != "")
+
+	var (
+		title      *markdownHeading
+		links      []*ast.Link
+		images     []*ast.Image
+		headings   []*markdownHeading
+		h1s        []*markdownHeading
+		rawHTMLs   []*ast.RawHTML
+		htmlBlocks []*ast.HTMLBlock
+	)
+	headingsByOldID := make(map[string]*markdownHeading)
+	// Error ignored because walker doesn't return errors.
+	_ = goldast.Walk(f.AST, func(n ast.Node) error {
+		switch n := n.(type) {
+		case *ast.Link:
+			links = append(links, n)
+		case *ast.Image:
+			images = append(images, n)
+		case *ast.Heading:
+			var mh *markdownHeading
+			if n == titleNode {
+				mh = c.newHeading(f, fidgen, n, options.Title)
+				title = mh
+			} else {
+				mh = c.newHeading(f, fidgen, n, "")
+			}
+			headings = append(headings, mh)
+			if mh.Level() == 1 {
+				h1s = append(h1s, mh)
+			}
+			headingsByOldID[mh.OldID] = mh
+		case *ast.RawHTML:
+			rawHTMLs = append(rawHTMLs, n)
+		case *ast.HTMLBlock:
+			htmlBlocks = append(htmlBlocks, n)
+		}
+		return nil
+	})
+
+	mf := &markdownFileItem{
+		Path:            filePath,
+		Section:         section,
+		Item:            item,
+		File:            f,
+		Links:           links,
+		Images:          images,
+		Headings:        headings,
+		HeadingsByOldID: headingsByOldID,
+		HTMLPairs:       attachedPairs(f.AST, rawhtml.GetPairs(ctx)),
+		RawHTMLs:        rawHTMLs,
+		HTMLBlocks:      htmlBlocks,
+		Absorb:          options.Absorb,
+		NoTOC:           options.TOC != nil && !*options.TOC,
+	}
+
+	// If only a section was included,
+	// the headings under its title move up to match.
+	if title != nil {
+		mf.Title = title
+		f.AST.RemoveChild(f.AST, title.AST)
+		if section != "" {
+			shift := mf.Title.Lvl - 1
+			for _, h := range mf.Headings {
+				h.Lvl -= shift
+			}
+		}
+	} else {
+		// The included file does not have a title.
+		// Generate one from the front matter or the TOC link.
+		text := item.Text
+		if options.Title != "" {
+			text = options.Title
+		}
+		heading := ast.NewHeading(1)
+		heading.AppendChild(
+			heading,
+			ast.NewString([]byte(text)),
+		)
+		heading.SetBlankPreviousLines(true)
+		mf.Title = c.newHeading(f, fidgen, heading, "")
+
+		// Push all existing headers down one level
+		// to make room for the new title
+		// if any of them is a level 1 header.
+		if len(h1s) > 0 {
+			for _, h := range mf.Headings {
+				h.Lvl++
+			}
+		}
+		mf.Headings = append([]*markdownHeading{mf.Title}, mf.Headings...)
+	}
+
+	if options.Offset != 0 {
+		for _, h := range mf.Headings {
+			h.Lvl += options.Offset
+		}
+	}
+
+	// If we're being absorbed, we'll need a TOC.
+	if mf.Absorb {
+		fileTOC, err := toc.Inspect(mf.File.AST, mf.File.Source, toc.Compact(true))
+		if err != nil {
+			return nil, err
+		}
+		if options.AbsorbDepth > 0 {
+			trimTOCItems(fileTOC.Items, options.AbsorbDepth)
+		}
+		mf.TOC = fileTOC
+	}
+
+	c.addFile(mf)
+	return mf, nil
+}
+
+// addFile records a collected file
+// so that links to it can be resolved.
+//
+// If a file is included in full, links to it go there.
+// Otherwise, links to it go to the first included section of it,
+// and headings in other included sections are reachable from there.
+func (c *collector) addFile(mf *markdownFileItem) {
+	prev, ok := c.files[mf.Path]
+	switch {
+	case !ok || mf.Section == "":
+		c.files[mf.Path] = mf
+
+	case prev.Section != "":
+		for id, h := range mf.HeadingsByOldID {
+			if _, ok := prev.HeadingsByOldID[id]; !ok {
+				prev.HeadingsByOldID[id] = h
+			}
+		}
+		mf.HeadingsByOldID = prev.HeadingsByOldID
+	}
+}
+
+type markdownGroupItem struct {
+	Item    *stitch.TextItem
+	Heading *markdownHeading
+
+	src []byte
+}
+
+func (*markdownGroupItem) markdownItem() {}
+
+func (c *collector) collectGroupItem(item *stitch.TextItem) *markdownGroupItem {
+	h := ast.NewHeading(1) // will be transformed
+	h.AppendChild(h, ast.NewString([]byte(item.Text)))
+	h.SetBlankPreviousLines(true)
+
+	id, _ := c.idGen.GenerateID(item.Text)
+	return &markdownGroupItem{
+		Item: item,
+		Heading: &markdownHeading{
+			AST: h,
+			ID:  id,
+			Lvl: h.Level,
+		},
+	}
+}
+
+type markdownEmbedItem struct {
+	Item        *stitch.EmbedItem
+	Section     *markdownSection
+	FilesByPath map[string]*markdownFileItem
+	Heading     *markdownHeading
+	SummaryFile *goldast.File
+
+	// Dir is the directory under the FS
+	// that paths in FilesByPath are relative to.
+	Dir string
+
+	src  []byte
+	link *ast.Link // link in the TOC; set by the transformer
+}
+
+var _ markdownItem = (*markdownEmbedItem)(nil)
+
+func (c *collector) collectEmbedItem(item *stitch.EmbedItem, cursor tree.Cursor[stitch.Item]) (*markdownEmbedItem, error) {
+	if cursor.ChildCount() > 0 {
+		return nil, errors.New("embed cannot have children")
+	}
+
+	embedPath := path.Join(c.Dir, item.Target)
+	for _, p := range c.Stack {
+		if p == embedPath {
+			return nil, fmt.Errorf("embed cycle: %v", strings.Join(append(c.Stack, embedPath), " -> "))
+		}
+	}
+	summaryStack := append(c.Stack, embedPath)
+
+	src, err := c.readFile(item.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	summaryFile := goldast.Parse(c.Parser, embedPath, src)
+	summary, err := stitch.ParseSummary(summaryFile)
+	if err != nil {
+		return nil, err
+	}
+
+	coll, err := (&collector{
+		Dir:        path.Join(c.Dir, path.Dir(item.Target)),
+		Parser:     c.Parser,
+		FS:         c.FS,
+		Slugger:    c.Slugger,
+		Profile:    c.Profile,
+		Vars:       c.Vars,
+		GlobIgnore: c.GlobIgnore,
+		idGen:      c.idGen,
+		inputs:     c.inputs,
+		warnings:   c.warnings,
+		Stack:      summaryStack,
+	}).Collect(summaryFile.Info, summary)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(coll.Sections) {
+	case 0:
+		// Unreachable: ParseSummary always returns at least one section.
+		return nil, errors.New("no sections found")
+	case 1:
+		// ok
+	default:
+		pos := summaryFile.Position(goldast.OffsetOf(coll.Sections[1].Title))
+		return nil, &goldast.Error{
+			Pos:    pos,
+			Err:    errors.New("unexpected section; expected only one section"),
+			Source: summaryFile.Info.Line(pos.Line),
+		}
+	}
+
+	section := coll.Sections[0]
+	var heading *markdownHeading
+
+	if h := section.Title; h != nil {
+		// Ignore the heading level in the summary file.
+		// It'll get whatever the depth of the embed is.
+		h.Level = 1
+		id, _ := c.idGen.GenerateID(string(goldast.Text(summaryFile.Source, h)))
+		heading = &markdownHeading{
+			AST: h,
+			ID:  id,
+			Lvl: h.Level,
+		}
+
+		// Unset the section title so it doesn't transform
+		// heading levels.
+		section.Title = nil
+	} else {
+		// The included file does not have a title.
+		// Generate one from the TOC link.
+		h := ast.NewHeading(1) // will be transformed
+		h.AppendChild(h, ast.NewString([]byte(item.Text)))
+		h.SetBlankPreviousLines(true)
+		id, _ := c.idGen.GenerateID(item.Text)
+		heading = &markdownHeading{
+			AST: h,
+			ID:  id,
+			Lvl: h.Level,
+		}
+	}
+
+	return &markdownEmbedItem{
+		Item:        item,
+		Section:     section,
+		FilesByPath: coll.FilesByPath,
+		SummaryFile: summaryFile,
+		Heading:     heading,
+		Dir:         coll.Dir,
+	}, nil
+}
+
+func (*markdownEmbedItem) markdownItem() {}
+
+type markdownHeading struct {
+	AST ast.Node
+	ID  string
+	Lvl int
+
+	// ID of the heading in the original file.
+	OldID string
+}
+
+// newHeading generates an ID for the given heading.
+//
+// If title is non-empty, it replaces the text of the heading.
+// Links to the heading's original text will still work.
+func (c *collector) newHeading(f *goldast.File, fgen *header.IDGen, h *ast.Heading, title string) *markdownHeading {
+	text := string(goldast.Text(f.Source, h))
+	oldID, _ := fgen.GenerateID(text)
+	if title != "" && title != text {
+		h.RemoveChildren(h)
+		h.AppendChild(h, ast.NewString([]byte(title)))
+		text = title
+	}
+	id, _ := c.idGen.GenerateID(text)
+	h.SetAttributeString("id", []byte(id)) // needed for toc.Inspect
+	return &markdownHeading{
+		AST:   h,
+		ID:    id,
+		OldID: oldID,
+		Lvl:   h.Level,
+	}
+}
+
+// titleHeading returns the heading that is the title of a document,
+// or nil if the document doesn't have one.
+//
+// If only a section was included, its heading is the title.
+// Otherwise, if the document has only one level 1 heading,
+// and it's the first element in the document,
+// then that is the title.
+func titleHeading(doc ast.Node, section bool) *ast.Heading {
+	var (
+		first *ast.Heading
+		h1s   int
+	)
+	// Error ignored because walker doesn't return errors.
+	_ = goldast.Walk(doc, func(n ast.Node) error {
+		if h, ok := n.(*ast.Heading); ok {
+			if first == nil {
+				first = h
+			}
+			if h.Level == 1 {
+				h1s++
+			}
+		}
+		return nil
+	})
+
+	switch {
+	case section:
+		return first
+	case first != nil && first.Level == 1 && h1s == 1 && first.PreviousSibling() == nil:
+		return first
+	default:
+		return nil
+	}
+}
+
+func (h *markdownHeading) Level() int {
+	return h.Lvl
+}
+
+// parsedFile is a parsed Markdown file and its front matter.
+type parsedFile struct {
+	File     *goldast.File
+	Context  parser.Context
+	Options  fileOptions
+	Warnings []error // problems in the front matter
+}
+
+// peekFile parses the Markdown file at the given path
+// to decide whether to include it.
+//
+// The result is kept until it's taken by takeFile,
+// so the file isn't parsed again when it's collected.
+// Callers must not modify it.
+func (c *collector) peekFile(p string) (*parsedFile, error) {
+	if pf, ok := c.parsed[p]; ok {
+		return pf, nil
+	}
+
+	pf, err := c.parseFile(p)
+	if err != nil {
+		return nil, err
+	}
+	if c.parsed == nil {
+		c.parsed = make(map[string]*parsedFile)
+	}
+	c.parsed[p] = pf
+	return pf, nil
+}
+
+// takeFile returns the parsed Markdown file at the given path,
+// reusing the result of an earlier peekFile if there is one.
+// The caller owns the result and may modify it.
+func (c *collector) takeFile(p string) (*parsedFile, error) {
+	if pf, ok := c.parsed[p]; ok {
+		delete(c.parsed, p)
+		return pf, nil
+	}
+	return c.parseFile(p)
+}
+
+// parseFile reads and parses the Markdown file at the given path
+// and its front matter.
+func (c *collector) parseFile(p string) (*parsedFile, error) {
+	src, err := c.readFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := parser.NewContext()
+	f := goldast.Parse(c.Parser, p, src, parser.WithContext(ctx))
+	options, warnings, err := readFileOptions(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsedFile{
+		File:     f,
+		Context:  ctx,
+		Options:  options,
+		Warnings: warnings,
+	}, nil
+}
+
+// readFile reads a file from the underlying filesystem.
+func (c *collector) readFile(p string) ([]byte, error) {
+	p = path.Join(c.Dir, filepath.ToSlash(p))
+	// Record the file even if it can't be read
+	// so that watchers notice when it's created.
+	c.inputs.Add(p)
+	src, err := fs.ReadFile(c.FS, p)
+	if err != nil {
+		// If the error is because the path name was not valid,
+		// it likely contains "." or ".." components,
+		// or has a "/" at the start or end of the path.
+		// Provide a hint to the user.
+		if errors.Is(err, fs.ErrInvalid) {
+			return nil, &hintError{
+				Err:  fmt.Errorf("invalid path %q", p),
+				Hint: "did you mean to use -unsafe?",
+			}
+		}
+		return nil, err
+	}
+	return src, nil
+}
+
+// pathSet is an ordered set of file paths.
+// The zero value is an empty set.
+type pathSet struct {
+	paths []string
+	seen  map[string]struct{}
+}
+
+// Add adds a path to the set if it isn't already present.
+func (s *pathSet) Add(p string) {
+	if _, ok := s.seen[p]; ok {
+		return
+	}
+	if s.seen == nil {
+		s.seen = make(map[string]struct{})
+	}
+	s.seen[p] = struct{}{}
+	s.paths = append(s.paths, p)
+}
+
+// Paths returns the paths in the set
+// in the order they were added.
+func (s *pathSet) Paths() []string {
+	return slices.Clone(s.paths)
+}
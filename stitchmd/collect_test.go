@@ -0,0 +1,236 @@
+package stitchmd
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/stitchmd/internal/goldast"
+	"go.abhg.dev/stitchmd/internal/stitch"
+	"go.abhg.dev/stitchmd/internal/tree"
+)
+
+func TestCollector_fileDoesNotExist(t *testing.T) {
+	t.Parallel()
+
+	file := goldast.Parse(
+		goldast.DefaultParser(),
+		"stdin",
+		[]byte("- [foo](foo.md)"),
+	)
+	summary, err := stitch.ParseSummary(file)
+	require.NoError(t, err)
+
+	_, err = (&collector{
+		Parser: goldast.DefaultParser(),
+		FS:     make(fstest.MapFS), // empty filesystem
+	}).Collect(file.Info, summary)
+	require.Error(t, err)
+
+	assert.ErrorContains(t, err, "foo.md: file does not exist")
+}
+
+func TestCollector_inputs(t *testing.T) {
+	t.Parallel()
+
+	file := goldast.Parse(
+		goldast.DefaultParser(),
+		"stdin",
+		[]byte("- [foo](foo.md)\n- ![bar](bar/summary.md)\n- [foo again](foo.md)\n"),
+	)
+	summary, err := stitch.ParseSummary(file)
+	require.NoError(t, err)
+
+	coll, err := (&collector{
+		Parser: goldast.DefaultParser(),
+		FS: fstest.MapFS{
+			"foo.md":         {Data: []byte("# Foo")},
+			"bar/summary.md": {Data: []byte("- [baz](baz.md)\n- [qux](qux.md)")},
+			"bar/baz.md":     {Data: []byte("# Baz")},
+		},
+	}).Collect(file.Info, summary)
+	require.Error(t, err, "bar/qux.md does not exist")
+
+	assert.Equal(t, []string{
+		"foo.md",
+		"bar/summary.md",
+		"bar/baz.md",
+		"bar/qux.md",
+	}, coll.Inputs)
+}
+
+func TestCollector_unknownItemType(t *testing.T) {
+	t.Parallel()
+
+	type badItem struct {
+		stitch.Item
+	}
+
+	summary := &stitch.Summary{
+		Sections: []*stitch.Section{
+			{Items: tree.List[stitch.Item]{
+				{Value: badItem{}},
+			}},
+		},
+	}
+
+	assert.Panics(t, func() {
+		_, _ = (&collector{
+			Parser: goldast.DefaultParser(),
+			FS:     make(fstest.MapFS),
+		}).Collect(fixedPositioner{Line: 1, Column: 1}, summary)
+	})
+}
+
+type fixedPositioner goldast.Position
+
+var _ goldast.Positioner = fixedPositioner{}
+
+func (p fixedPositioner) Position(int) goldast.Position {
+	return goldast.Position(p)
+}
+
+func TestCollector_globErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc    string
+		give    string
+		wantErr string
+	}{
+		{
+			desc:    "no matches",
+			give:    "- [ADRs](adr/*.md)",
+			wantErr: `stdin:1:3:glob "adr/*.md" did not match any files`,
+		},
+		{
+			desc:    "children",
+			give:    "- [Docs](docs/*.md)\n    - [foo](foo.md)",
+			wantErr: "stdin:1:3:glob cannot have children",
+		},
+		{
+			desc:    "not plain text",
+			give:    "- [*Docs*](docs/*.md)",
+			wantErr: "stdin:1:3:glob text must be plain text",
+		},
+		{
+			desc:    "bad pattern",
+			give:    "- [Docs](docs/[*.md)",
+			wantErr: "syntax error in pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			file := goldast.Parse(goldast.DefaultParser(), "stdin", []byte(tt.give))
+			summary, err := stitch.ParseSummary(file)
+			require.NoError(t, err)
+
+			_, err = (&collector{
+				Parser: goldast.DefaultParser(),
+				FS: fstest.MapFS{
+					"docs/foo.md": {Data: []byte("# Foo")},
+					"foo.md":      {Data: []byte("# Foo")},
+				},
+			}).Collect(file.Info, summary)
+			require.Error(t, err)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCollector_globInputs(t *testing.T) {
+	t.Parallel()
+
+	file := goldast.Parse(
+		goldast.DefaultParser(),
+		"stdin",
+		[]byte("- [Docs](docs/*.md)\n"),
+	)
+	summary, err := stitch.ParseSummary(file)
+	require.NoError(t, err)
+
+	coll, err := (&collector{
+		Parser: goldast.DefaultParser(),
+		FS: fstest.MapFS{
+			"docs/b.md": {Data: []byte("# B")},
+			"docs/a.md": {Data: []byte("# A")},
+		},
+	}).Collect(file.Info, summary)
+	require.NoError(t, err)
+
+	// The directory is recorded so that
+	// new files matching the glob are noticed.
+	assert.Equal(t, []string{
+		"docs",
+		"docs/a.md",
+		"docs/b.md",
+	}, coll.Inputs)
+}
+
+func TestCollector_sectionErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc    string
+		give    string
+		wantErr string
+	}{
+		{
+			desc:    "no such heading",
+			give:    "- [Foo](foo.md#nope)",
+			wantErr: `foo.md: no heading with ID "nope"`,
+		},
+		{
+			desc:    "nested heading",
+			give:    "- [Quoted](foo.md#quoted)",
+			wantErr: `foo.md: heading "quoted" must be at the top level of the file`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			file := goldast.Parse(goldast.DefaultParser(), "stdin", []byte(tt.give))
+			summary, err := stitch.ParseSummary(file)
+			require.NoError(t, err)
+
+			_, err = (&collector{
+				Parser: goldast.DefaultParser(),
+				FS: fstest.MapFS{
+					"foo.md": {Data: []byte("# Foo\n\n> ## Quoted\n")},
+				},
+			}).Collect(file.Info, summary)
+			require.Error(t, err)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCollector_codeIncludeErrors(t *testing.T) {
+	t.Parallel()
+
+	file := goldast.Parse(
+		goldast.DefaultParser(),
+		"stdin",
+		[]byte("- [foo](foo.md)\n"),
+	)
+	summary, err := stitch.ParseSummary(file)
+	require.NoError(t, err)
+
+	_, err = (&collector{
+		Parser: goldast.DefaultParser(),
+		FS: fstest.MapFS{
+			"foo.md": {Data: []byte("# Foo\n\n```go include=missing.go\n```\n\n```go include=bar.go region=nope\n```\n")},
+			"bar.go": {Data: []byte("package bar\n")},
+		},
+	}).Collect(file.Info, summary)
+	require.Error(t, err)
+
+	assert.ErrorContains(t, err, "foo.md:3:4:include missing.go: open missing.go: file does not exist")
+	assert.ErrorContains(t, err, `foo.md:6:4:include bar.go: region "nope" not found`)
+}
@@ -0,0 +1,36 @@
+package stitchmd_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"testing/fstest"
+
+	"go.abhg.dev/stitchmd/stitchmd"
+)
+
+func ExampleStitch() {
+	out, err := stitchmd.Stitch(context.Background(), stitchmd.Options{
+		FS: fstest.MapFS{
+			"intro.md":   {Data: []byte("# Introduction\n\nHello.\n")},
+			"install.md": {Data: []byte("# Installation\n\nRun the installer.\n")},
+		},
+		Summary: []byte("- [Intro](intro.md)\n- [Install](install.md)\n"),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(string(out))
+
+	// Output:
+	// - [Intro](#introduction)
+	// - [Install](#installation)
+	//
+	// # Introduction
+	//
+	// Hello.
+	//
+	// # Installation
+	//
+	// Run the installer.
+}
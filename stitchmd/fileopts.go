@@ -0,0 +1,152 @@
+package stitchmd
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"go.abhg.dev/goldmark/frontmatter"
+	"go.abhg.dev/goldmark/toc"
+	"go.abhg.dev/stitchmd/internal/goldast"
+)
+
+// fileOptions are options for an included Markdown file
+// specified in its front matter.
+type fileOptions struct {
+	// Headings included in the file
+	// should be absorbed into the parent TOC.
+	Absorb bool `yaml:"absorb" toml:"absorb"`
+
+	// Maximum depth of absorbed headings.
+	// Zero means no limit.
+	AbsorbDepth int `yaml:"absorb_depth" toml:"absorb_depth"`
+
+	// Title to use instead of the file's own title.
+	Title string `yaml:"title" toml:"title"`
+
+	// Amount to shift the levels of headings in the file by.
+	Offset int `yaml:"offset" toml:"offset"`
+
+	// Whether the file should be listed in the summary's TOC.
+	// Defaults to true.
+	TOC *bool `yaml:"toc" toml:"toc"`
+
+	// Leave the file out of the output.
+	Skip bool `yaml:"skip" toml:"skip"`
+
+	// Position of the file in glob items.
+	Order *int `yaml:"order" toml:"order"`
+
+	// Expand variables inside code spans and code blocks too.
+	CodeVars bool `yaml:"code_vars" toml:"code_vars"`
+
+	// Profiles that the file is limited to.
+	// The file is skipped when building other profiles.
+	Profiles []string `yaml:"profiles" toml:"profiles"`
+}
+
+// _fileOptionKeys is the set of keys recognized in front matter.
+var _fileOptionKeys = func() map[string]struct{} {
+	keys := make(map[string]struct{})
+	typ := reflect.TypeOf(fileOptions{})
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("yaml"), ",")
+		keys[name] = struct{}{}
+	}
+	return keys
+}()
+
+// readFileOptions decodes the front matter of a parsed file, if any.
+//
+// Keys that aren't recognized are reported as warnings.
+func readFileOptions(ctx parser.Context, f *goldast.File) (opts fileOptions, warnings []error, err error) {
+	data := frontmatter.Get(ctx)
+	if data == nil {
+		return opts, nil, nil
+	}
+
+	if err := data.Decode(&opts); err != nil {
+		return opts, nil, fmt.Errorf("bad frontmatter: %v", err)
+	}
+	if opts.AbsorbDepth < 0 {
+		return opts, nil, fmt.Errorf("bad frontmatter: absorb_depth must not be negative")
+	}
+
+	var raw map[string]any
+	if err := data.Decode(&raw); err != nil {
+		return opts, nil, fmt.Errorf("bad frontmatter: %v", err)
+	}
+	unknown := make([]string, 0, len(raw))
+	for key := range raw {
+		if _, ok := _fileOptionKeys[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+
+	for _, key := range unknown {
+		pos := f.Info.Position(goldast.FrontMatterKeyOffset(f.Source, key))
+		warnings = append(warnings, &goldast.Error{
+			Pos:    pos,
+			Err:    fmt.Errorf("unknown front matter key %q", key),
+			Source: f.Info.Line(pos.Line),
+		})
+	}
+	return opts, warnings, nil
+}
+
+// trimTOCItems drops items nested deeper than the given depth.
+// Top-level items are at depth 1.
+func trimTOCItems(items toc.Items, depth int) {
+	for _, item := range items {
+		if depth <= 1 {
+			item.Items = nil
+		} else {
+			trimTOCItems(item.Items, depth-1)
+		}
+	}
+}
+
+// removeTOCItem removes the list item holding the given node
+// from the summary's TOC.
+//
+// If keepChildren is true, items nested under it take its place.
+// Otherwise, they're removed with it.
+func removeTOCItem(n ast.Node, keepChildren bool) {
+	var item *ast.ListItem
+	for ; n != nil; n = n.Parent() {
+		if li, ok := n.(*ast.ListItem); ok {
+			item = li
+			break
+		}
+	}
+	if item == nil {
+		return
+	}
+
+	list := item.Parent()
+	if keepChildren {
+		for c := item.FirstChild(); c != nil; c = c.NextSibling() {
+			nested, ok := c.(*ast.List)
+			if !ok {
+				continue
+			}
+			for li := nested.FirstChild(); li != nil; {
+				next := li.NextSibling()
+				list.InsertBefore(list, item, li)
+				li = next
+			}
+		}
+	}
+	list.RemoveChild(list, item)
+
+	// Don't leave an empty nested list behind.
+	if list.ChildCount() == 0 {
+		if parent, ok := list.Parent().(*ast.ListItem); ok {
+			parent.RemoveChild(parent, list)
+		}
+	}
+}
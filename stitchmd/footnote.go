@@ -0,0 +1,172 @@
+package stitchmd
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+)
+
+// Footnotes are numbered per-file by the parser.
+// Once files are stitched together, these numbers collide,
+// so the transformer renumbers them to be unique across the output,
+// and the generator renders them in a way suitable for the output format.
+
+// renumberFootnotes renumbers the footnotes in the given file
+// so that they come after all footnotes seen so far.
+//
+// Footnotes retain their relative order.
+func (t *transformer) renumberFootnotes(doc ast.Node) {
+	var (
+		links     []*extast.FootnoteLink
+		backlinks []*extast.FootnoteBacklink
+		notes     []*extast.Footnote
+		indexes   = make(map[int]int) // old index => new index
+	)
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch n := n.(type) {
+		case *extast.FootnoteLink:
+			links = append(links, n)
+		case *extast.FootnoteBacklink:
+			backlinks = append(backlinks, n)
+		case *extast.Footnote:
+			notes = append(notes, n)
+			indexes[n.Index] = 0
+		}
+		return ast.WalkContinue, nil
+	})
+	if len(notes) == 0 {
+		return
+	}
+
+	old := make([]int, 0, len(indexes))
+	for idx := range indexes {
+		old = append(old, idx)
+	}
+	sort.Ints(old)
+	for _, idx := range old {
+		*t.footnotes++
+		indexes[idx] = *t.footnotes
+	}
+
+	for _, n := range links {
+		n.Index = indexes[n.Index]
+	}
+	for _, n := range backlinks {
+		n.Index = indexes[n.Index]
+	}
+	for _, n := range notes {
+		n.Index = indexes[n.Index]
+		n.Ref = strconv.AppendInt(nil, int64(n.Index), 10)
+	}
+}
+
+// takeFootnoteList removes the list of footnotes from the given document
+// and returns it.
+// It returns nil if the document has no footnotes.
+func takeFootnoteList(doc ast.Node) *extast.FootnoteList {
+	for n := doc.LastChild(); n != nil; n = n.PreviousSibling() {
+		if list, ok := n.(*extast.FootnoteList); ok {
+			doc.RemoveChild(doc, list)
+			return list
+		}
+	}
+	return nil
+}
+
+// lowerFootnotes replaces footnotes in a document
+// with nodes that the Markdown renderer supports.
+//
+// References become [^N] and definitions become paragraphs
+// in the form "[^N]: text" at the end of the document.
+func (g *generator) lowerFootnotes(src []byte, doc ast.Node) error {
+	list := takeFootnoteList(doc)
+	if list == nil {
+		return nil
+	}
+	doc.AppendChild(doc, list) // so that links inside it are lowered
+
+	var nodes []ast.Node
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch n.(type) {
+		case *extast.FootnoteLink, *extast.FootnoteBacklink:
+			if entering {
+				nodes = append(nodes, n)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, n := range nodes {
+		parent := n.Parent()
+		switch n := n.(type) {
+		case *extast.FootnoteLink:
+			ref := ast.NewString([]byte("[^" + strconv.Itoa(n.Index) + "]"))
+			ref.SetRaw(true)
+			parent.ReplaceChild(parent, n, ref)
+		case *extast.FootnoteBacklink:
+			parent.RemoveChild(parent, n)
+		}
+	}
+	doc.RemoveChild(doc, list)
+
+	for n := list.FirstChild(); n != nil; n = n.NextSibling() {
+		note := n.(*extast.Footnote)
+
+		// Render the footnote contents on their own,
+		// and indent all but the first line under the label.
+		body := ast.NewDocument()
+		for c := note.FirstChild(); c != nil; {
+			next := c.NextSibling()
+			body.AppendChild(body, c)
+			c = next
+		}
+		var buf bytes.Buffer
+		if err := g.Renderer.Render(&buf, src, body); err != nil {
+			return err
+		}
+
+		var def bytes.Buffer
+		def.WriteString("[^" + strconv.Itoa(note.Index) + "]: ")
+		for i, line := range bytes.Split(bytes.TrimRight(buf.Bytes(), "\n"), []byte("\n")) {
+			if i > 0 {
+				def.WriteByte('\n')
+				if len(line) > 0 {
+					def.WriteString("    ")
+				}
+			}
+			def.Write(line)
+		}
+
+		text := ast.NewString(def.Bytes())
+		text.SetRaw(true)
+		para := ast.NewParagraph()
+		para.AppendChild(para, text)
+		doc.AppendChild(doc, para)
+	}
+	return nil
+}
+
+// renderFootnotesHTML renders the footnote definitions in the given document
+// into w as HTML list items, and removes them from the document.
+//
+// The caller is responsible for wrapping them in a list.
+func (g *generator) renderFootnotesHTML(w io.Writer, src []byte, doc ast.Node) error {
+	list := takeFootnoteList(doc)
+	if list == nil {
+		return nil
+	}
+
+	for n := list.FirstChild(); n != nil; n = n.NextSibling() {
+		if err := g.Renderer.Render(w, src, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package stitchmd
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+
+	mdfmt "github.com/Kunde21/markdownfmt/v3/markdown"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	goldhtml "github.com/yuin/goldmark/renderer/html"
+	"go.abhg.dev/stitchmd/internal/goldast"
+)
+
+type generator struct {
+	headingIdx int
+
+	Preface  []byte
+	W        io.Writer         // required
+	Renderer renderer.Renderer // required
+	NoTOC    bool
+
+	// TOCDepth is the maximum depth of items in the TOC.
+	// Top-level items are at depth 1.
+	// Zero means no limit.
+	TOCDepth int
+
+	// TOCStyle is the style in which the TOC is rendered.
+	TOCStyle TOCStyle
+
+	// Format of the output.
+	// For HTML, the output is wrapped in a standalone HTML document.
+	Format Format
+
+	NoSectionTitle bool
+
+	// Footnotes rendered so far for HTML output.
+	// These are written at the end of the document.
+	footnotes *bytes.Buffer
+}
+
+// newRenderer builds a renderer for the given output format.
+func newRenderer(format Format) renderer.Renderer {
+	switch format {
+	case FormatHTML:
+		return goldmark.New(
+			goldmark.WithExtensions(extension.GFM, extension.Footnote),
+			goldmark.WithRendererOptions(
+				// Summary and included files are trusted input.
+				// Raw HTML in them must be retained.
+				goldhtml.WithUnsafe(),
+			),
+		).Renderer()
+
+	default:
+		render := mdfmt.NewRenderer()
+		render.AddMarkdownOptions(
+			mdfmt.WithSoftWraps(),
+		)
+		return render
+	}
+}
+
+func (g *generator) Generate(src []byte, coll *markdownCollection) error {
+	if g.Format == FormatHTML {
+		_, _ = io.WriteString(g.W, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+		if title := documentTitle(src, coll); title != "" {
+			_, _ = fmt.Fprintf(g.W, "<title>%s</title>\n", html.EscapeString(title))
+		}
+		_, _ = io.WriteString(g.W, "</head>\n<body>\n")
+		g.footnotes = new(bytes.Buffer)
+	}
+
+	if _, err := g.W.Write(g.Preface); err != nil {
+		return err
+	}
+
+	if err := g.generate(src, coll); err != nil {
+		return err
+	}
+
+	if g.Format == FormatHTML {
+		// Footnotes from all files go into a single list
+		// so that their numbers match their position in the list.
+		if g.footnotes.Len() > 0 {
+			_, _ = io.WriteString(g.W, "<div class=\"footnotes\" role=\"doc-endnotes\">\n<hr>\n<ol>\n")
+			_, _ = g.footnotes.WriteTo(g.W)
+			_, _ = io.WriteString(g.W, "</ol>\n</div>\n")
+		}
+		_, _ = io.WriteString(g.W, "</body>\n</html>\n")
+	}
+	return nil
+}
+
+// documentTitle picks a title for a standalone document
+// built from the given collection.
+//
+// This is the title of the first section, if any,
+// or the title of the first item in it.
+func documentTitle(src []byte, coll *markdownCollection) string {
+	if len(coll.Sections) == 0 {
+		return ""
+	}
+
+	sec := coll.Sections[0]
+	if sec.Title != nil {
+		return string(goldast.Text(src, sec.Title))
+	}
+
+	if len(sec.Items) == 0 {
+		return ""
+	}
+	switch item := sec.Items[0].Value.(type) {
+	case *markdownFileItem:
+		return string(goldast.Text(item.File.Source, item.Title.AST))
+	case *markdownGroupItem:
+		return item.Item.Text
+	case *markdownEmbedItem:
+		return string(goldast.Text(item.SummaryFile.Source, item.Heading.AST))
+	default:
+		return ""
+	}
+}
+
+func (g *generator) generate(src []byte, coll *markdownCollection) error {
+	for _, sec := range coll.Sections {
+		if err := g.renderSection(src, sec); err != nil {
+			return err
+		}
+		if err := sec.Items.Walk(g.renderItem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) renderSection(src []byte, sec *markdownSection) error {
+	var nodes []ast.Node
+	if t := sec.Title; !g.NoSectionTitle && t != nil {
+		nodes = append(nodes, t)
+	}
+	if !g.NoTOC {
+		// The TOC items are not part of the content,
+		// so they can be changed in-place.
+		if g.TOCDepth > 0 {
+			trimTOCList(sec.TOCItems, g.TOCDepth)
+		}
+		if g.TOCStyle == TOCNumbered {
+			orderTOCList(sec.TOCItems)
+		}
+		nodes = append(nodes, sec.TOCItems)
+	}
+
+	for _, n := range nodes {
+		isTOC := n == sec.TOCItems
+		if isTOC {
+			g.startTOC()
+		}
+		if err := g.Renderer.Render(g.W, src, n); err != nil {
+			return err
+		}
+		if isTOC {
+			g.endTOC()
+		}
+	}
+
+	if len(nodes) > 0 {
+		_, _ = io.WriteString(g.W, "\n\n")
+	}
+	return nil
+}
+
+// startTOC writes what goes before the TOC
+// based on the output format and the TOC style.
+func (g *generator) startTOC() {
+	if g.Format == FormatHTML {
+		_, _ = io.WriteString(g.W, "<nav>\n")
+	}
+	if g.TOCStyle == TOCDetails {
+		_, _ = io.WriteString(g.W, "<details>\n<summary>Contents</summary>\n")
+		if g.Format != FormatHTML {
+			// Markdown inside HTML blocks must be separated
+			// from the HTML by a blank line.
+			_, _ = io.WriteString(g.W, "\n")
+		}
+	}
+}
+
+// endTOC writes what goes after the TOC.
+// It's the counterpart to startTOC.
+func (g *generator) endTOC() {
+	if g.TOCStyle == TOCDetails {
+		if g.Format == FormatHTML {
+			_, _ = io.WriteString(g.W, "</details>\n")
+		} else {
+			// Like the list before it,
+			// this doesn't end with a newline.
+			_, _ = io.WriteString(g.W, "\n\n</details>")
+		}
+	}
+	if g.Format == FormatHTML {
+		_, _ = io.WriteString(g.W, "</nav>\n")
+	}
+}
+
+// trimTOCList removes lists nested deeper than the given depth
+// from a TOC.
+// Items in the top-level list are at depth 1.
+func trimTOCList(list ast.Node, depth int) {
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		for c := item.FirstChild(); c != nil; {
+			next := c.NextSibling()
+			if nested, ok := c.(*ast.List); ok {
+				if depth <= 1 {
+					item.RemoveChild(item, nested)
+				} else {
+					trimTOCList(nested, depth-1)
+				}
+			}
+			c = next
+		}
+	}
+}
+
+// orderTOCList turns a TOC and all lists nested inside it
+// into ordered lists.
+func orderTOCList(list *ast.List) {
+	_ = ast.Walk(list, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if l, ok := n.(*ast.List); ok && entering {
+			l.Marker = '.'
+			l.Start = 1
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+func (g *generator) addHeadingSep() {
+	if g.headingIdx > 0 {
+		_, _ = io.WriteString(g.W, "\n")
+	}
+	g.headingIdx++
+}
+
+func (g *generator) renderItem(item markdownItem) error {
+	switch item := item.(type) {
+	case *markdownGroupItem:
+		return g.renderGroupItem(item)
+
+	case *markdownFileItem:
+		return g.renderFileItem(item)
+
+	case *markdownEmbedItem:
+		return g.renderEmbedItem(item)
+
+	case *markdownExternalLinkItem:
+		// Nothing to do.
+		// The item was already rendered in the TOC.
+		return nil
+
+	default:
+		panic(fmt.Sprintf("unknown markdown item type %T", item))
+	}
+}
+
+func (g *generator) renderGroupItem(group *markdownGroupItem) error {
+	g.addHeadingSep()
+	if err := g.Renderer.Render(g.W, group.src, group.Heading.AST); err != nil {
+		return err
+	}
+	_, _ = io.WriteString(g.W, "\n")
+	return nil
+}
+
+func (g *generator) renderEmbedItem(embed *markdownEmbedItem) error {
+	g.addHeadingSep()
+	if err := g.Renderer.Render(g.W, embed.SummaryFile.Source, embed.Heading.AST); err != nil {
+		return err
+	}
+
+	_, _ = io.WriteString(g.W, "\n")
+
+	return (&generator{
+		W:              g.W,
+		Renderer:       g.Renderer,
+		Format:         g.Format,
+		NoTOC:          true,
+		NoSectionTitle: true,
+		headingIdx:     g.headingIdx,
+		footnotes:      g.footnotes,
+	}).generate(embed.SummaryFile.Source, &markdownCollection{
+		Sections:    []*markdownSection{embed.Section},
+		FilesByPath: embed.FilesByPath,
+	})
+}
+
+func (g *generator) renderFileItem(file *markdownFileItem) error {
+	g.addHeadingSep()
+
+	src, doc := file.File.Source, file.File.AST
+	if g.Format == FormatHTML {
+		if err := g.renderFootnotesHTML(g.footnotes, src, doc); err != nil {
+			return err
+		}
+	} else {
+		if err := g.lowerFootnotes(src, doc); err != nil {
+			return err
+		}
+	}
+
+	return g.Renderer.Render(g.W, src, doc)
+}
@@ -0,0 +1,242 @@
+package stitchmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"go.abhg.dev/stitchmd/internal/goldast"
+	"go.abhg.dev/stitchmd/internal/stitch"
+	"go.abhg.dev/stitchmd/internal/tree"
+)
+
+// Glob items in the summary are expanded before collection.
+// Each glob item becomes a group item
+// with a link item for each matching file as its children.
+// The summary's TOC is updated to match so that the files appear in it.
+
+// expandGlobs replaces glob items in the given list
+// (and its descendants) with group items.
+// Errors are reported to errs and the offending items are dropped.
+func (c *collector) expandGlobs(errs *goldast.ErrorList, items tree.List[stitch.Item]) tree.List[stitch.Item] {
+	out := make(tree.List[stitch.Item], 0, len(items))
+	for _, n := range items {
+		glob, ok := n.Value.(*stitch.GlobItem)
+		if !ok {
+			n.List = c.expandGlobs(errs, n.List)
+			out = append(out, n)
+			continue
+		}
+
+		expanded, err := c.expandGlob(glob, len(n.List))
+		if err != nil {
+			errs.Pushf(glob.AST, "%w", err)
+			continue
+		}
+		out = append(out, expanded)
+	}
+	return out
+}
+
+// globMatch is a single file matched by a glob item.
+type globMatch struct {
+	Path  string // relative to collector's Dir
+	Title string
+	Order *int
+	Skip  bool
+}
+
+func (c *collector) expandGlob(glob *stitch.GlobItem, childCount int) (*tree.Node[stitch.Item], error) {
+	if childCount > 0 {
+		return nil, errors.New("glob cannot have children")
+	}
+
+	// The link text becomes the text of the group,
+	// so it must be plain text.
+	text, ok := glob.AST.FirstChild().(*ast.Text)
+	if !ok || glob.AST.ChildCount() != 1 {
+		return nil, errors.New("glob text must be plain text")
+	}
+
+	matches, err := c.globFiles(glob.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("glob %q did not match any files", glob.Pattern)
+	}
+
+	// Replace "[Foo](foo/*.md)" in the TOC with "Foo",
+	// and list the matched files under it.
+	link := glob.AST
+	parent := link.Parent()
+	parent.ReplaceChild(parent, link, text)
+
+	var listItem *ast.ListItem
+	for n := parent; n != nil; n = n.Parent() {
+		if li, ok := n.(*ast.ListItem); ok {
+			listItem = li
+			break
+		}
+	}
+	if listItem == nil {
+		return nil, errors.New("glob must be a list item")
+	}
+
+	list := ast.NewList(listItem.Parent().(*ast.List).Marker)
+	listItem.AppendChild(listItem, list)
+
+	node := &tree.Node[stitch.Item]{
+		Value: &stitch.TextItem{
+			Text:  glob.Text,
+			Depth: glob.Depth,
+			AST:   text,
+		},
+	}
+	for _, m := range matches {
+		title := ast.NewString([]byte(m.Title))
+		title.SetRaw(true)
+
+		link := ast.NewLink()
+		link.Destination = []byte(m.Path)
+		link.AppendChild(link, title)
+
+		block := ast.NewTextBlock()
+		block.AppendChild(block, link)
+
+		item := ast.NewListItem(0)
+		item.AppendChild(item, block)
+		list.AppendChild(list, item)
+
+		node.List = append(node.List, &tree.Node[stitch.Item]{
+			Value: &stitch.LinkItem{
+				Text:   m.Title,
+				Target: m.Path,
+				Depth:  glob.Depth + 1,
+				AST:    link,
+			},
+		})
+	}
+
+	return node, nil
+}
+
+// globFiles reports the files matching the given pattern,
+// sorted by the "order" field in their front matter,
+// and then by path.
+// Files with an order come before those without one.
+//
+// Summary files that are currently being collected,
+// files in GlobIgnore, files that ask to be skipped,
+// and files limited to other profiles are never matched.
+func (c *collector) globFiles(pattern string) ([]*globMatch, error) {
+	pattern = path.Join(c.Dir, pattern)
+	paths, err := fs.Glob(c.FS, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	// fs.Glob ignores errors reading directories,
+	// so a pattern that leaves the FS would match nothing.
+	// Report it the same way as a link that leaves the FS.
+	if len(paths) == 0 && !fs.ValidPath(pattern) {
+		if _, err := fs.Stat(c.FS, path.Dir(pattern)); errors.Is(err, fs.ErrInvalid) {
+			return nil, &hintError{
+				Err:  fmt.Errorf("invalid path %q", pattern),
+				Hint: "did you mean to use -unsafe?",
+			}
+		}
+	}
+
+	// Record the directory being matched against
+	// so that watchers notice when files are added to it.
+	if dir := path.Dir(pattern); !strings.ContainsAny(dir, `*?[\`) {
+		c.inputs.Add(dir)
+	}
+
+	var matches []*globMatch
+	for _, p := range paths {
+		if slices.Contains(c.Stack, p) || slices.Contains(c.GlobIgnore, p) {
+			continue
+		}
+		if info, err := fs.Stat(c.FS, p); err != nil || info.IsDir() {
+			continue
+		}
+
+		rel := p
+		if c.Dir != "" && c.Dir != "." {
+			rel = strings.TrimPrefix(p, c.Dir+"/")
+		}
+
+		m, err := c.inspectGlobMatch(rel)
+		if err != nil {
+			return nil, err
+		}
+		if m.Skip {
+			// The file won't be collected.
+			delete(c.parsed, rel)
+			continue
+		}
+		matches = append(matches, m)
+	}
+
+	slices.SortStableFunc(matches, func(a, b *globMatch) int {
+		switch {
+		case a.Order != nil && b.Order != nil:
+			if c := cmp.Compare(*a.Order, *b.Order); c != 0 {
+				return c
+			}
+		case a.Order != nil:
+			return -1
+		case b.Order != nil:
+			return 1
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+	return matches, nil
+}
+
+// inspectGlobMatch reads the front matter and title of a matched file.
+//
+// The title is the title set in the front matter, if any,
+// or the file's level 1 heading
+// if it's the only one and it's the first element in the file,
+// matching how titles are picked in collectFileItem.
+// Otherwise, the file name without the extension is used.
+func (c *collector) inspectGlobMatch(p string) (*globMatch, error) {
+	// Warnings are ignored here.
+	// They're reported when the file is collected.
+	pf, err := c.peekFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", p, err)
+	}
+	f, options := pf.File, pf.Options
+
+	var h1s []*ast.Heading
+	_ = goldast.Walk(f.AST, func(n ast.Node) error {
+		if h, ok := n.(*ast.Heading); ok && h.Level == 1 {
+			h1s = append(h1s, h)
+		}
+		return nil
+	})
+
+	title := strings.TrimSuffix(path.Base(p), path.Ext(p))
+	switch {
+	case options.Title != "":
+		title = options.Title
+	case len(h1s) == 1 && h1s[0].PreviousSibling() == nil:
+		title = string(goldast.Text(f.Source, h1s[0]))
+	}
+
+	return &globMatch{
+		Path:  p,
+		Title: title,
+		Order: options.Order,
+		Skip:  options.Skip || !stitch.MatchProfile(options.Profiles, c.Profile),
+	}, nil
+}
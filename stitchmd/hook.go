@@ -0,0 +1,166 @@
+package stitchmd
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/yuin/goldmark/ast"
+	"go.abhg.dev/stitchmd/internal/tree"
+)
+
+// Hook inspects or changes included files
+// before the output is generated.
+//
+// Use it to add rewrites that stitchmd doesn't support,
+// for example, turning blockquotes into HTML callouts.
+type Hook interface {
+	// TransformFile is called with each included file
+	// in the order they appear in the output.
+	// Errors are reported by Run.
+	TransformFile(ctx context.Context, f *File) error
+}
+
+// HookFunc is a Hook that calls the function.
+type HookFunc func(ctx context.Context, f *File) error
+
+var _ Hook = HookFunc(nil)
+
+// TransformFile calls the function.
+func (fn HookFunc) TransformFile(ctx context.Context, f *File) error {
+	return fn(ctx, f)
+}
+
+// File is a Markdown file included in the output
+// as seen by a Hook.
+//
+// By the time hooks see it, the file is in its final form:
+// links have been rewritten to point into the output,
+// and headings have been moved to their final levels and IDs.
+type File struct {
+	// Path is the /-separated path to the file in Options.FS.
+	Path string
+
+	// Item is the item in the summary that included the file.
+	Item SummaryItem
+
+	// AST is the parsed file, including its title.
+	// Hooks may change it.
+	AST ast.Node
+
+	// Source is the text that nodes in AST point into.
+	//
+	// Hooks that add nodes with new text
+	// should append the text to Source and point the nodes at it.
+	Source []byte
+
+	// Title is the title of the file.
+	// It's also the first heading in Headings.
+	Title *Heading
+
+	// Headings lists the headings in the file.
+	Headings []*Heading
+}
+
+// SummaryItem is an item in the summary file.
+type SummaryItem struct {
+	// Text is the text of the item.
+	Text string
+
+	// Target is the destination of the item's link
+	// as written in the summary.
+	Target string
+
+	// Depth is the depth of the item in the summary.
+	// Top-level items are at depth 0.
+	Depth int
+}
+
+// Heading is a heading in an included file.
+type Heading struct {
+	// AST is the node for the heading.
+	// This is an *ast.Heading unless the heading is too deep
+	// to represent in Markdown,
+	// in which case it's an *ast.Paragraph.
+	AST ast.Node
+
+	// ID is the ID of the heading in the output.
+	// Changing it does not change links to the heading.
+	ID string
+
+	// Level is the level of the heading in the output.
+	// It may be greater than 6.
+	Level int
+}
+
+// runHooks calls the given hooks with all files in the collection.
+//
+// This must be called after the collection has been transformed.
+func runHooks(ctx context.Context, hooks []Hook, coll *markdownCollection) error {
+	var visit func(coll *markdownCollection, items tree.List[markdownItem]) error
+	visit = func(coll *markdownCollection, items tree.List[markdownItem]) error {
+		for _, n := range items {
+			switch item := n.Value.(type) {
+			case *markdownFileItem:
+				if err := runFileHooks(ctx, hooks, coll.Dir, item); err != nil {
+					return err
+				}
+
+			case *markdownEmbedItem:
+				embedded := &markdownCollection{
+					Sections:    []*markdownSection{item.Section},
+					FilesByPath: item.FilesByPath,
+					Dir:         item.Dir,
+				}
+				if err := visit(embedded, item.Section.Items); err != nil {
+					return err
+				}
+			}
+
+			if err := visit(coll, n.List); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, sec := range coll.Sections {
+		if err := visit(coll, sec.Items); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runFileHooks(ctx context.Context, hooks []Hook, dir string, item *markdownFileItem) error {
+	headings := make([]*Heading, len(item.Headings))
+	var title *Heading
+	for i, h := range item.Headings {
+		headings[i] = &Heading{AST: h.AST, ID: h.ID, Level: h.Lvl}
+		if h == item.Title {
+			title = headings[i]
+		}
+	}
+
+	f := &File{
+		Path: path.Join(dir, item.Path),
+		Item: SummaryItem{
+			Text:   item.Item.Text,
+			Target: item.Item.Target,
+			Depth:  item.Item.Depth,
+		},
+		AST:      item.File.AST,
+		Source:   item.File.Source,
+		Title:    title,
+		Headings: headings,
+	}
+	for _, hook := range hooks {
+		if err := hook.TransformFile(ctx, f); err != nil {
+			return fmt.Errorf("%v: %w", f.Path, err)
+		}
+	}
+
+	item.File.AST = f.AST
+	item.File.Source = f.Source
+	return nil
+}
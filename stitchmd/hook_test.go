@@ -0,0 +1,151 @@
+package stitchmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"go.abhg.dev/stitchmd/internal/goldast"
+)
+
+func TestRun_hooks(t *testing.T) {
+	t.Parallel()
+
+	type seenFile struct {
+		Path     string
+		Item     SummaryItem
+		Title    string
+		Headings []Heading
+	}
+
+	var seen []seenFile
+	record := HookFunc(func(_ context.Context, f *File) error {
+		headings := make([]Heading, len(f.Headings))
+		for i, h := range f.Headings {
+			headings[i] = Heading{ID: h.ID, Level: h.Level}
+		}
+		seen = append(seen, seenFile{
+			Path:     f.Path,
+			Item:     f.Item,
+			Title:    string(goldast.Text(f.Source, f.Title.AST)),
+			Headings: headings,
+		})
+		return nil
+	})
+
+	// Appends a paragraph with new text to the end of each file.
+	footer := HookFunc(func(_ context.Context, f *File) error {
+		start := len(f.Source)
+		f.Source = append(f.Source, "Back to top."...)
+
+		para := ast.NewParagraph()
+		para.SetBlankPreviousLines(true)
+		para.AppendChild(para, ast.NewTextSegment(text.NewSegment(start, len(f.Source))))
+		f.AST.AppendChild(f.AST, para)
+		return nil
+	})
+
+	got, err := Stitch(context.Background(), Options{
+		FS: fstest.MapFS{
+			"intro.md":           {Data: []byte("# Introduction\n\n## Goals\n")},
+			"plugins/summary.md": {Data: []byte("- [Foo](foo.md)\n")},
+			"plugins/foo.md":     {Data: []byte("# Foo")},
+		},
+		Summary: []byte("- [Intro](intro.md)\n  - ![Plugins](plugins/summary.md)\n"),
+		Hooks:   []Hook{record, footer},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []seenFile{
+		{
+			Path:  "intro.md",
+			Item:  SummaryItem{Text: "Intro", Target: "intro.md"},
+			Title: "Introduction",
+			Headings: []Heading{
+				{ID: "introduction", Level: 1},
+				{ID: "goals", Level: 2},
+			},
+		},
+		{
+			Path:     "plugins/foo.md",
+			Item:     SummaryItem{Text: "Foo", Target: "foo.md"},
+			Title:    "Foo",
+			Headings: []Heading{{ID: "foo", Level: 3}},
+		},
+	}, seen)
+
+	assert.Equal(t, `- [Intro](#introduction)
+  - [Plugins](#plugins)
+    - [Foo](#foo)
+
+# Introduction
+
+## Goals
+
+Back to top.
+
+## Plugins
+
+### Foo
+
+Back to top.
+`, string(got))
+}
+
+func TestRun_hookError(t *testing.T) {
+	t.Parallel()
+
+	giveErr := errors.New("great sadness")
+	_, err := Run(context.Background(), Options{
+		FS: fstest.MapFS{
+			"foo.md": {Data: []byte("# Foo")},
+		},
+		Summary: []byte("- [Foo](foo.md)\n"),
+		Hooks: []Hook{
+			HookFunc(func(context.Context, *File) error {
+				return giveErr
+			}),
+		},
+	})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, giveErr)
+	assert.EqualError(t, err, "foo.md: great sadness")
+}
+
+func TestRun_astTransformers(t *testing.T) {
+	t.Parallel()
+
+	got, err := Stitch(context.Background(), Options{
+		FS: fstest.MapFS{
+			"foo.md": {Data: []byte("# Foo\n\n<!-- internal -->\n\nHello.\n")},
+		},
+		Summary: []byte("- [Foo](foo.md)\n"),
+		ASTTransformers: []util.PrioritizedValue{
+			util.Prioritized(dropCommentsTransformer{}, 200),
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "- [Foo](#foo)\n\n# Foo\n\nHello.\n", string(got))
+}
+
+// dropCommentsTransformer removes HTML comment blocks.
+type dropCommentsTransformer struct{}
+
+func (dropCommentsTransformer) Transform(doc *ast.Document, _ text.Reader, _ parser.Context) {
+	var comments []ast.Node
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		if b, ok := n.(*ast.HTMLBlock); ok && b.HTMLBlockType == ast.HTMLBlockType2 {
+			comments = append(comments, n)
+		}
+	}
+	for _, n := range comments {
+		doc.RemoveChild(doc, n)
+	}
+}
@@ -0,0 +1,111 @@
+package stitchmd
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+
+	"go.abhg.dev/stitchmd/internal/goldast"
+)
+
+// linkChecker verifies that relative links in included Markdown files
+// point to files that exist, and headings that exist in those files.
+//
+// It must run before the transformer rewrites the links.
+type linkChecker struct {
+	// FS is used to check whether files outside the collection exist.
+	// Paths are resolved against the collection's Dir.
+	FS fs.FS // required
+
+	seen map[*markdownFileItem]struct{}
+}
+
+// Check reports all broken links in the collection.
+// Each error is prefixed with the position of the link.
+func (lc *linkChecker) Check(coll *markdownCollection) []error {
+	lc.seen = make(map[*markdownFileItem]struct{})
+
+	var errs []error
+	lc.checkCollection(&errs, coll)
+	return errs
+}
+
+func (lc *linkChecker) checkCollection(errs *[]error, coll *markdownCollection) {
+	for _, sec := range coll.Sections {
+		_ = sec.Items.Walk(func(item markdownItem) error {
+			switch item := item.(type) {
+			case *markdownFileItem:
+				*errs = append(*errs, lc.checkFile(coll, item)...)
+
+			case *markdownEmbedItem:
+				lc.checkCollection(errs, &markdownCollection{
+					Sections:    []*markdownSection{item.Section},
+					FilesByPath: item.FilesByPath,
+					Dir:         item.Dir,
+				})
+			}
+			return nil
+		})
+	}
+}
+
+func (lc *linkChecker) checkFile(coll *markdownCollection, f *markdownFileItem) []error {
+	if _, ok := lc.seen[f]; ok {
+		return nil
+	}
+	lc.seen[f] = struct{}{}
+
+	errs := goldast.NewInlineErrorList(f.File.Info)
+	fromPath := path.Dir(f.Path)
+	for _, l := range f.Links {
+		if err := lc.checkURL(coll, fromPath, f, string(l.Destination)); err != nil {
+			errs.Pushf(l, "%v", err)
+		}
+	}
+	for _, i := range f.Images {
+		if err := lc.checkURL(coll, fromPath, f, string(i.Destination)); err != nil {
+			errs.Pushf(i, "%v", err)
+		}
+	}
+
+	// ErrorList joins the errors; split them back up
+	// so that the caller can report them individually.
+	err := errs.Err()
+	if err == nil {
+		return nil
+	}
+	return splitErrors(err)
+}
+
+// checkURL follows the same resolution rules as transformer.transformURL.
+func (lc *linkChecker) checkURL(coll *markdownCollection, fromPath string, f *markdownFileItem, toURL string) error {
+	u, err := url.Parse(toURL)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return nil
+	}
+
+	to := f
+	if u.Path != "" {
+		dst := path.Join(fromPath, u.Path)
+		var ok bool
+		to, ok = coll.FilesByPath[dst]
+		if !ok {
+			// Not a Markdown file in the collection.
+			// We can only check that it exists.
+			if _, err := fs.Stat(lc.FS, path.Join(coll.Dir, dst)); err != nil {
+				return fmt.Errorf("broken link %q: file not found", toURL)
+			}
+			return nil
+		}
+	}
+
+	if u.Fragment == "" {
+		return nil
+	}
+
+	if _, ok := to.HeadingsByOldID[u.Fragment]; !ok {
+		return fmt.Errorf("broken link %q: no heading with ID %q in %v", toURL, u.Fragment, to.Path)
+	}
+	return nil
+}
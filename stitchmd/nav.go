@@ -0,0 +1,100 @@
+package stitchmd
+
+import (
+	"fmt"
+
+	"github.com/yuin/goldmark/ast"
+	"go.abhg.dev/stitchmd/internal/tree"
+)
+
+// With -nav, each included file is followed by a line
+// linking to the previous and next files in the summary,
+// and to the item it's nested under:
+//
+//	[← Installation](#installation) | [Up: Getting started](#getting-started) | [Configuration →](#configuration)
+//
+// Files are ordered as they appear in the output,
+// including files from embedded summaries.
+
+// navPage is a file in the order it appears in the output.
+type navPage struct {
+	File *markdownFileItem
+
+	// Item that the file is nested under, if any.
+	// This is a file, group, or embed item.
+	Up markdownItem
+}
+
+// addNav adds navigation links to the end of all files in the collection.
+//
+// This must be called after all items have been transformed
+// so that heading IDs are final.
+func (t *transformer) addNav(coll *markdownCollection) {
+	var (
+		pages []navPage
+		visit func(items tree.List[markdownItem], up markdownItem)
+	)
+	visit = func(items tree.List[markdownItem], up markdownItem) {
+		for _, n := range items {
+			switch item := n.Value.(type) {
+			case *markdownFileItem:
+				pages = append(pages, navPage{File: item, Up: up})
+			case *markdownEmbedItem:
+				visit(item.Section.Items, item)
+			}
+			visit(n.List, n.Value)
+		}
+	}
+	for _, sec := range coll.Sections {
+		visit(sec.Items, nil)
+	}
+
+	for i, page := range pages {
+		nav := ast.NewParagraph()
+		nav.SetBlankPreviousLines(true)
+
+		addLink := func(item markdownItem, format string) {
+			text, id := navTarget(item)
+			if nav.HasChildren() {
+				nav.AppendChild(nav, ast.NewString([]byte(" | ")))
+			}
+			link := ast.NewLink()
+			link.Destination = []byte("#" + id)
+			link.AppendChild(link, ast.NewString(fmt.Appendf(nil, format, text)))
+			nav.AppendChild(nav, link)
+		}
+
+		if i > 0 {
+			addLink(pages[i-1].File, "← %s")
+		}
+		if page.Up != nil {
+			addLink(page.Up, "Up: %s")
+		}
+		if i < len(pages)-1 {
+			addLink(pages[i+1].File, "%s →")
+		}
+
+		if nav.HasChildren() {
+			doc := page.File.File.AST
+			doc.AppendChild(doc, nav)
+		}
+	}
+}
+
+// navTarget reports the text and heading ID to use
+// when linking to the given item.
+//
+// The text is the item's text in the summary
+// so that navigation links match the TOC.
+func navTarget(item markdownItem) (text, id string) {
+	switch item := item.(type) {
+	case *markdownFileItem:
+		return item.Item.Text, item.Title.ID
+	case *markdownGroupItem:
+		return item.Item.Text, item.Heading.ID
+	case *markdownEmbedItem:
+		return item.Item.Text, item.Heading.ID
+	default:
+		panic(fmt.Sprintf("unexpected navigation target %T", item))
+	}
+}
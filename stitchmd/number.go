@@ -0,0 +1,183 @@
+package stitchmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/yuin/goldmark/ast"
+	"go.abhg.dev/stitchmd/internal/goldast"
+	"go.abhg.dev/stitchmd/internal/header"
+	"go.abhg.dev/stitchmd/internal/tree"
+)
+
+// With -number, titles of items in the summary are numbered
+// by their position in the summary, like "1.2.3 Installing".
+// The same numbers are added to the links in the TOC.
+//
+// Heading IDs are generated from the titles without the numbers,
+// so links to them don't change when items are added or moved.
+//
+// Markdown renderers generate IDs for headings from their text,
+// including the numbers.
+// Numbered titles therefore need anchors with their generated IDs.
+// So do other headings whose generated IDs were made unique
+// against the titles without the numbers,
+// because the renderer makes them unique against the titles with the numbers.
+
+// assignNumbers records numbers for the given items and their descendants.
+//
+// count is the number of items already numbered at this level
+// with the same prefix.
+func (t *transformer) assignNumbers(items tree.List[markdownItem], prefix string, count *int) {
+	for _, n := range items {
+		if _, ok := n.Value.(*markdownExternalLinkItem); ok {
+			// External links don't have a title in the output.
+			continue
+		}
+
+		*count++
+		num := strconv.Itoa(*count)
+		if prefix != "" {
+			num = prefix + "." + num
+		}
+		t.numbers[n.Value] = num
+
+		var childCount int
+		t.assignNumbers(n.List, num, &childCount)
+	}
+}
+
+// numberItem adds the number assigned to the given item, if any,
+// to its title and its link in the TOC.
+//
+// This must be called after the item has been transformed.
+func (t *transformer) numberItem(item markdownItem) {
+	num, ok := t.numbers[item]
+	if !ok {
+		return
+	}
+
+	switch item := item.(type) {
+	case *markdownFileItem:
+		t.numberHeading(item.Title, num)
+		prependNumber(item.Item.AST, num)
+	case *markdownGroupItem:
+		t.numberHeading(item.Heading, num)
+		prependNumber(item.Item.AST.Parent(), num)
+	case *markdownEmbedItem:
+		t.numberHeading(item.Heading, num)
+		prependNumber(item.link, num)
+	}
+}
+
+// numberHeading adds the given number to the text of a heading.
+func (t *transformer) numberHeading(h *markdownHeading, num string) {
+	switch n := h.AST.(type) {
+	case *ast.Heading:
+		// The anchor for the heading is added by anchorHeadings.
+		prependNumber(n, num)
+
+	case *ast.Paragraph:
+		// Headings that are too deep to represent in Markdown
+		// are bold text after an anchor.
+		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+			if em, ok := c.(*ast.Emphasis); ok {
+				prependNumber(em, num)
+				break
+			}
+		}
+	}
+}
+
+// outputHeading is a heading in the output
+// with the ID generated for it, if any.
+type outputHeading struct {
+	AST *ast.Heading
+	Src []byte
+	ID  string
+}
+
+// anchorHeadings adds anchors to headings in the output
+// that won't get their generated IDs from the Markdown renderer.
+//
+// This must be called after all items have been numbered.
+func (t *transformer) anchorHeadings(coll *markdownCollection) {
+	var headings []outputHeading
+	for _, sec := range coll.Sections {
+		// Section titles don't have generated IDs,
+		// but they take up IDs in the renderer.
+		if sec.Title != nil {
+			headings = append(headings, outputHeading{AST: sec.Title, Src: t.SummaryFile.Source})
+		}
+		headings = appendOutputHeadings(headings, t.SummaryFile.Source, sec.Items)
+	}
+
+	idgen := header.NewIDGen(t.Slugger)
+	for _, h := range headings {
+		autoID, _ := idgen.GenerateID(string(goldast.Text(h.Src, h.AST)))
+		if h.ID == "" || h.ID == autoID {
+			continue
+		}
+
+		anchor := ast.NewString(fmt.Appendf(nil, "<a id=%q></a>", h.ID))
+		anchor.SetRaw(true)
+		if first := h.AST.FirstChild(); first != nil {
+			h.AST.InsertBefore(h.AST, first, anchor)
+		} else {
+			h.AST.AppendChild(h.AST, anchor)
+		}
+	}
+}
+
+// appendOutputHeadings appends the headings of the given items
+// to the list in the order that they appear in the output.
+func appendOutputHeadings(headings []outputHeading, src []byte, items tree.List[markdownItem]) []outputHeading {
+	add := func(src []byte, h *markdownHeading) {
+		// Headings that are too deep to represent in Markdown
+		// already have anchors.
+		if n, ok := h.AST.(*ast.Heading); ok {
+			headings = append(headings, outputHeading{AST: n, Src: src, ID: h.ID})
+		}
+	}
+
+	// Error ignored because walker doesn't return errors.
+	_ = items.Walk(func(item markdownItem) error {
+		switch item := item.(type) {
+		case *markdownGroupItem:
+			add(src, item.Heading)
+
+		case *markdownEmbedItem:
+			add(item.SummaryFile.Source, item.Heading)
+			headings = appendOutputHeadings(headings, item.SummaryFile.Source, item.Section.Items)
+
+		case *markdownFileItem:
+			ids := make(map[ast.Node]string, len(item.Headings))
+			for _, h := range item.Headings {
+				ids[h.AST] = h.ID
+			}
+
+			_ = goldast.Walk(item.File.AST, func(n ast.Node) error {
+				if h, ok := n.(*ast.Heading); ok {
+					headings = append(headings, outputHeading{AST: h, Src: item.File.Source, ID: ids[h]})
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	return headings
+}
+
+// prependNumber adds the given number to the start of the node's text.
+func prependNumber(n ast.Node, num string) {
+	if n == nil {
+		return
+	}
+
+	s := ast.NewString([]byte(num + " "))
+	if first := n.FirstChild(); first != nil {
+		n.InsertBefore(n, first, s)
+	} else {
+		n.AppendChild(n, s)
+	}
+}
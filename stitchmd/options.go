@@ -0,0 +1,194 @@
+package stitchmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"go.abhg.dev/stitchmd/internal/header"
+)
+
+// Format specifies the format of the generated output.
+//
+// Format may be used as a flag.Value,
+// and decoded from text in configuration files.
+type Format int
+
+const (
+	// FormatMarkdown is the default.
+	FormatMarkdown Format = iota
+
+	// FormatHTML is a standalone HTML document.
+	FormatHTML
+)
+
+var _ flag.Getter = (*Format)(nil)
+
+// String reports the name of the format.
+func (f Format) String() string {
+	switch f {
+	case FormatMarkdown:
+		return "markdown"
+	case FormatHTML:
+		return "html"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(f))
+	}
+}
+
+// Get implements flag.Getter.
+func (f Format) Get() interface{} {
+	return f
+}
+
+// Set sets the format from its name.
+func (f *Format) Set(s string) error {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "markdown", "md":
+		*f = FormatMarkdown
+	case "html":
+		*f = FormatHTML
+	default:
+		return errors.New("must be one of 'markdown', 'html'")
+	}
+	return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+// so that the format may be specified in configuration files.
+func (f *Format) UnmarshalText(b []byte) error {
+	return f.Set(string(b))
+}
+
+// TOCStyle specifies how the table of contents is rendered.
+//
+// Like Format, TOCStyle may be used as a flag.Value.
+type TOCStyle int
+
+const (
+	// TOCList is a bulleted list. This is the default.
+	TOCList TOCStyle = iota
+
+	// TOCDetails is a bulleted list
+	// inside a collapsible <details> block.
+	TOCDetails
+
+	// TOCNumbered is an ordered list.
+	TOCNumbered
+)
+
+var _ flag.Getter = (*TOCStyle)(nil)
+
+// String reports the name of the style.
+func (s TOCStyle) String() string {
+	switch s {
+	case TOCList:
+		return "list"
+	case TOCDetails:
+		return "details"
+	case TOCNumbered:
+		return "numbered"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(s))
+	}
+}
+
+// Get implements flag.Getter.
+func (s TOCStyle) Get() interface{} {
+	return s
+}
+
+// Set sets the style from its name.
+func (s *TOCStyle) Set(str string) error {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "list":
+		*s = TOCList
+	case "details":
+		*s = TOCDetails
+	case "numbered":
+		*s = TOCNumbered
+	default:
+		return errors.New("must be one of 'list', 'details', 'numbered'")
+	}
+	return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+// so that the style may be specified in configuration files.
+func (s *TOCStyle) UnmarshalText(b []byte) error {
+	return s.Set(string(b))
+}
+
+// SlugStyle specifies the rules used to generate heading IDs.
+// These should match the renderer that will display the output.
+//
+// Like Format, SlugStyle may be used as a flag.Value.
+type SlugStyle int
+
+const (
+	SlugGitHub SlugStyle = iota // default; also Gitea and Forgejo
+	SlugGitLab
+	SlugPandoc
+	SlugHugo
+)
+
+var _ flag.Getter = (*SlugStyle)(nil)
+
+// String reports the name of the style.
+func (s SlugStyle) String() string {
+	switch s {
+	case SlugGitHub:
+		return "github"
+	case SlugGitLab:
+		return "gitlab"
+	case SlugPandoc:
+		return "pandoc"
+	case SlugHugo:
+		return "hugo"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(s))
+	}
+}
+
+// Get implements flag.Getter.
+func (s SlugStyle) Get() interface{} {
+	return s
+}
+
+// Set sets the style from its name.
+func (s *SlugStyle) Set(str string) error {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "github", "gitea", "forgejo":
+		*s = SlugGitHub
+	case "gitlab":
+		*s = SlugGitLab
+	case "pandoc":
+		*s = SlugPandoc
+	case "hugo":
+		*s = SlugHugo
+	default:
+		return errors.New("must be one of 'github', 'gitlab', 'pandoc', 'hugo'")
+	}
+	return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+// so that the style may be specified in configuration files.
+func (s *SlugStyle) UnmarshalText(b []byte) error {
+	return s.Set(string(b))
+}
+
+// slugger returns the header.Slugger implementing this style.
+func (s SlugStyle) slugger() header.Slugger {
+	switch s {
+	case SlugGitLab:
+		return header.GitLab
+	case SlugPandoc:
+		return header.Pandoc
+	case SlugHugo:
+		return header.Hugo
+	default:
+		return header.GitHub
+	}
+}
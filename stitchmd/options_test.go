@@ -0,0 +1,112 @@
+package stitchmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		give       string
+		want       Format
+		wantString string
+	}{
+		{give: "markdown", want: FormatMarkdown, wantString: "markdown"},
+		{give: "md", want: FormatMarkdown, wantString: "markdown"},
+		{give: "html", want: FormatHTML, wantString: "html"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.give, func(t *testing.T) {
+			t.Parallel()
+
+			var got Format
+			require.NoError(t, got.Set(tt.give))
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want, got.Get())
+			assert.Equal(t, tt.wantString, got.String())
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, "unknown (42)", Format(42).String())
+	})
+}
+
+func TestTOCStyle(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		give       string
+		want       TOCStyle
+		wantString string
+	}{
+		{give: "list", want: TOCList, wantString: "list"},
+		{give: "Details", want: TOCDetails, wantString: "details"},
+		{give: "numbered", want: TOCNumbered, wantString: "numbered"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.give, func(t *testing.T) {
+			t.Parallel()
+
+			var got TOCStyle
+			require.NoError(t, got.Set(tt.give))
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want, got.Get())
+			assert.Equal(t, tt.wantString, got.String())
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		t.Parallel()
+
+		var got TOCStyle
+		assert.Error(t, got.Set("table"))
+		assert.Equal(t, "unknown (42)", TOCStyle(42).String())
+	})
+}
+
+func TestSlugStyle(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		give       string
+		want       SlugStyle
+		wantString string
+	}{
+		{give: "github", want: SlugGitHub, wantString: "github"},
+		{give: "gitea", want: SlugGitHub, wantString: "github"},
+		{give: "GitLab", want: SlugGitLab, wantString: "gitlab"},
+		{give: "pandoc", want: SlugPandoc, wantString: "pandoc"},
+		{give: "hugo", want: SlugHugo, wantString: "hugo"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.give, func(t *testing.T) {
+			t.Parallel()
+
+			var got SlugStyle
+			require.NoError(t, got.Set(tt.give))
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want, got.Get())
+			assert.Equal(t, tt.wantString, got.String())
+			assert.NotNil(t, got.slugger())
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, "unknown (42)", SlugStyle(42).String())
+	})
+}
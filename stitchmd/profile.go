@@ -0,0 +1,107 @@
+package stitchmd
+
+import (
+	"github.com/yuin/goldmark/ast"
+	"go.abhg.dev/stitchmd/internal/goldast"
+	"go.abhg.dev/stitchmd/internal/stitch"
+	"go.abhg.dev/stitchmd/internal/tree"
+)
+
+// Summary items, included files, and blocks of content in included files
+// may be limited to certain profiles.
+// Only those matching the profile selected with -profile are included.
+//
+// Blocks of content are fenced with HTML comments:
+//
+//	<!-- profile: internal -->
+//	Reach out to #docs-team with questions.
+//	<!-- end profile -->
+
+// dropProfiles removes items that aren't part of the collector's profile
+// from the list and from the summary's TOC,
+// along with all items nested under them.
+func (c *collector) dropProfiles(items tree.List[stitch.Item]) tree.List[stitch.Item] {
+	out := make(tree.List[stitch.Item], 0, len(items))
+	for _, n := range items {
+		if stitch.MatchProfile(n.Value.ItemProfiles(), c.Profile) {
+			n.List = c.dropProfiles(n.List)
+			out = append(out, n)
+			continue
+		}
+
+		removeTOCItem(n.Value.Node(), false /* keepChildren */)
+	}
+	return out
+}
+
+// filterProfileBlocks removes blocks of content
+// that aren't part of the collector's profile from the given file.
+// The comments fencing these blocks are always removed.
+//
+// Fences may be nested, but each must start and end
+// inside the same parent node.
+func (c *collector) filterProfileBlocks(f *goldast.File) error {
+	errs := goldast.NewErrorList(f.Info)
+
+	var filter func(parent ast.Node)
+	filter = func(parent ast.Node) {
+		var (
+			// Fences that haven't been closed yet.
+			open []ast.Node
+
+			// Number of open fences that don't match the profile.
+			// Content is dropped if this is non-zero.
+			excluded int
+
+			remove []ast.Node
+		)
+		for n := parent.FirstChild(); n != nil; n = n.NextSibling() {
+			if block, ok := n.(*ast.HTMLBlock); ok {
+				comment := string(block.Lines().Value(f.Source))
+				if profiles, ok := stitch.ParseProfileStart(comment); ok {
+					open = append(open, block)
+					if !stitch.MatchProfile(profiles, c.Profile) {
+						excluded++
+					}
+					remove = append(remove, n)
+					continue
+				}
+
+				if stitch.IsProfileEnd(comment) {
+					if len(open) == 0 {
+						errs.Pushf(n, "unexpected end of profile block")
+						continue
+					}
+
+					start := open[len(open)-1]
+					open = open[:len(open)-1]
+					profiles, _ := stitch.ParseProfileStart(string(start.Lines().Value(f.Source)))
+					if !stitch.MatchProfile(profiles, c.Profile) {
+						excluded--
+					}
+					remove = append(remove, n)
+					continue
+				}
+			}
+
+			if excluded > 0 {
+				remove = append(remove, n)
+			} else if n.Type() == ast.TypeBlock {
+				filter(n)
+			}
+		}
+
+		for _, n := range open {
+			errs.Pushf(n, "profile block is never closed")
+		}
+		for _, n := range remove {
+			parent.RemoveChild(parent, n)
+		}
+	}
+	filter(f.AST)
+
+	// Footnotes referenced only from removed blocks
+	// are removed with them.
+	trimFootnotes(f.AST)
+	return errs.Err()
+}
@@ -0,0 +1,134 @@
+package stitchmd
+
+import (
+	"fmt"
+
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"go.abhg.dev/stitchmd/internal/goldast"
+	"go.abhg.dev/stitchmd/internal/header"
+	"go.abhg.dev/stitchmd/internal/rawhtml"
+)
+
+// extractSection removes everything from the given file
+// except the heading with the given ID
+// and the content under it,
+// up to the next heading of the same or higher level.
+//
+// fidgen is the generator for IDs of headings in the original file.
+// Headings before the section are fed into it
+// so that IDs of the remaining headings match the original file.
+func (c *collector) extractSection(f *goldast.File, fidgen *header.IDGen, id string) error {
+	var headings []*ast.Heading
+	_ = goldast.Walk(f.AST, func(n ast.Node) error {
+		if h, ok := n.(*ast.Heading); ok {
+			headings = append(headings, h)
+		}
+		return nil
+	})
+
+	idgen := header.NewIDGen(c.Slugger)
+	idx := -1
+	for i, h := range headings {
+		if hid, _ := idgen.GenerateID(string(goldast.Text(f.Source, h))); hid == id {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return fmt.Errorf("no heading with ID %q", id)
+	}
+	for _, h := range headings[:idx] {
+		_, _ = fidgen.GenerateID(string(goldast.Text(f.Source, h)))
+	}
+
+	start := headings[idx]
+	doc := f.AST
+	if start.Parent() != doc {
+		return fmt.Errorf("heading %q must be at the top level of the file", id)
+	}
+
+	var (
+		remove  []ast.Node
+		inside  bool
+		endSeen bool
+	)
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		if _, ok := n.(*extast.FootnoteList); ok {
+			continue // trimmed below
+		}
+
+		switch {
+		case n == start:
+			inside = true
+			continue
+		case inside && !endSeen:
+			if h, ok := n.(*ast.Heading); ok && h.Level <= start.Level {
+				endSeen = true
+			}
+		}
+
+		if !inside || endSeen {
+			remove = append(remove, n)
+		}
+	}
+	for _, n := range remove {
+		doc.RemoveChild(doc, n)
+	}
+
+	trimFootnotes(doc)
+	return nil
+}
+
+// trimFootnotes removes footnote definitions
+// that are no longer referenced in the document.
+func trimFootnotes(doc ast.Node) {
+	list := takeFootnoteList(doc)
+	if list == nil {
+		return
+	}
+
+	used := make(map[int]struct{})
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if link, ok := n.(*extast.FootnoteLink); ok && entering {
+			used[link.Index] = struct{}{}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for n := list.FirstChild(); n != nil; {
+		next := n.NextSibling()
+		if note, ok := n.(*extast.Footnote); ok {
+			if _, ok := used[note.Index]; !ok {
+				list.RemoveChild(list, n)
+			}
+		}
+		n = next
+	}
+
+	if list.ChildCount() > 0 {
+		doc.AppendChild(doc, list)
+	}
+}
+
+// attachedPairs returns the HTML pairs
+// that are still part of the given document.
+func attachedPairs(doc ast.Node, pairs rawhtml.Pairs) rawhtml.Pairs {
+	var out rawhtml.Pairs
+	for _, p := range pairs {
+		if isAttached(doc, p.Open) && isAttached(doc, p.Close) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// isAttached reports whether n is a descendant of doc.
+func isAttached(doc, n ast.Node) bool {
+	for ; n != nil; n = n.Parent() {
+		if n == doc {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,416 @@
+// Package stitchmd combines Markdown files into a single document
+// following the table of contents in a summary file.
+//
+// This is the library behind the stitchmd command.
+// See https://github.com/abhinav/stitchmd for the format of summary files
+// and the features available to included files.
+package stitchmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/util"
+	"go.abhg.dev/stitchmd/internal/goldast"
+	"go.abhg.dev/stitchmd/internal/rawhtml"
+	"go.abhg.dev/stitchmd/internal/stitch"
+)
+
+// Options specifies what to stitch and how.
+type Options struct {
+	// FS holds the Markdown files referenced by the summary.
+	// Paths in the summary are relative to its root.
+	FS fs.FS // required
+
+	// Summary is the contents of the summary file.
+	Summary []byte // required
+
+	// SummaryPath is the /-separated path to the summary file in FS,
+	// if it's in FS.
+	// It's used in error messages,
+	// and keeps the summary from including itself.
+	SummaryPath string
+
+	// OutputPath is the /-separated path in FS
+	// that the output will be written to, if any.
+	// Glob items in the summary never match it.
+	OutputPath string
+
+	// InputRelPath is the /-separated path to the root of FS
+	// from the directory the output will be written to.
+	// Links to files that aren't part of the output
+	// are rewritten to go through it.
+	//
+	// Defaults to ".".
+	InputRelPath string
+
+	// LinkFS is used to check that links to files
+	// that aren't part of the output point to files that exist.
+	//
+	// Defaults to FS.
+	LinkFS fs.FS
+
+	// AssetsPath, if set, bundles files referenced from included files
+	// that aren't part of the output into a single directory.
+	// It's the /-separated path to that directory
+	// from the directory the output will be written to.
+	//
+	// Use [Result.WriteAssets] to write the bundled files.
+	AssetsPath string
+
+	// Preface is added to the top of the output verbatim.
+	Preface []byte
+
+	// Offset is added to the levels of all headings.
+	Offset int
+
+	// NoTOC specifies that the table of contents
+	// should be left out of the output.
+	NoTOC bool
+
+	// TOCDepth is the number of levels of items
+	// to include in the table of contents.
+	// Zero means all levels.
+	TOCDepth int
+
+	// TOCStyle is the style in which the table of contents is rendered.
+	TOCStyle TOCStyle
+
+	// Number specifies that titles of items and their links in the TOC
+	// should be numbered by their position in the summary.
+	Number bool
+
+	// Nav specifies that each included file should end with links
+	// to the previous and next files, and the item it's nested under.
+	Nav bool
+
+	// Format is the format of the output.
+	Format Format
+
+	// Slug specifies the rules used to generate heading IDs.
+	Slug SlugStyle
+
+	// Profile selects which items and blocks of content
+	// limited to certain profiles are included.
+	Profile string
+
+	// Vars holds values of variables
+	// that included files may reference with {{ .Name }}.
+	Vars map[string]string
+
+	// Strict specifies that warnings should be reported as errors.
+	Strict bool
+
+	// Warn, if set, is called with warnings found
+	// in front matter and links of included files.
+	// Warnings are grouped the same way Strict would report them.
+	// They're otherwise ignored unless Strict is set.
+	Warn func(*InputError)
+
+	// Hooks are called with each included file
+	// after it has been prepared for the output,
+	// and before the output is generated.
+	Hooks []Hook
+
+	// ASTTransformers are added to the goldmark parser
+	// used for the summary and included files,
+	// alongside those that stitchmd uses.
+	//
+	//	ASTTransformers: []util.PrioritizedValue{
+	//		util.Prioritized(&myTransformer{}, 100),
+	//	},
+	ASTTransformers []util.PrioritizedValue
+}
+
+// warn reports the given warnings with Options.Warn.
+// With Strict, it returns them in an InputError instead.
+func (o *Options) warn(code, format string, warnings []error) error {
+	if len(warnings) == 0 {
+		return nil
+	}
+	err := &InputError{
+		Code: code,
+		Msg:  fmt.Sprintf(format, len(warnings)),
+		Errs: warnings,
+	}
+	if o.Strict {
+		return err
+	}
+	if o.Warn != nil {
+		o.Warn(err)
+	}
+	return nil
+}
+
+// Result is the result of stitching with Run.
+type Result struct {
+	// Output is the stitched document.
+	Output []byte
+
+	// Inputs lists the /-separated paths in Options.FS
+	// of the files that were read, including bundled assets.
+	// The summary isn't included.
+	//
+	// Inputs is reported even if Run fails after reading files
+	// so that callers may watch them for changes.
+	Inputs []string
+
+	assets *assetBundler
+}
+
+// WriteAssets writes files bundled with Options.AssetsPath
+// into the given directory, creating it if necessary.
+// It does nothing if no files were bundled.
+func (r *Result) WriteAssets(dir string) error {
+	if r.assets == nil {
+		return nil
+	}
+	return r.assets.WriteTo(dir)
+}
+
+// Codes identify the kind of problems reported in an InputError.
+const (
+	// CodeSummary is used for problems in the summary file.
+	CodeSummary = "summary"
+
+	// CodeInput is used for problems reading the files
+	// that the summary references.
+	CodeInput = "input"
+
+	// CodeFrontMatter is used for unknown keys
+	// in the front matter of included files.
+	CodeFrontMatter = "front-matter"
+
+	// CodeBrokenLink is used for links to files that don't exist.
+	CodeBrokenLink = "broken-link"
+)
+
+// InputError reports problems with the summary
+// or the files it references.
+type InputError struct {
+	// Code identifies the kind of problems, for example, [CodeBrokenLink].
+	Code string
+
+	// Msg briefly describes the problems,
+	// for example, "found 2 broken link(s)".
+	Msg string
+
+	// Errs lists the problems.
+	// Those at known positions in input files are [*Error]s.
+	//
+	// Problems in files included by a summary item
+	// are reported at the position of that item,
+	// with the Err of that [*Error] holding the problems
+	// at their own positions in the included file.
+	Errs []error
+}
+
+func (e *InputError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(e.Msg)
+	for _, err := range e.Errs {
+		sb.WriteString("\n")
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap returns the individual problems.
+func (e *InputError) Unwrap() []error {
+	return e.Errs
+}
+
+// Error is a problem at a known position in an input file.
+// Use errors.As to retrieve it from errors returned by Stitch.
+type Error = goldast.Error
+
+// Hint returns a suggestion for fixing the given problem,
+// or an empty string if there isn't one.
+//
+// The suggestion is already part of the error message.
+func Hint(err error) string {
+	var hintErr *hintError
+	if errors.As(err, &hintErr) {
+		return hintErr.Hint
+	}
+	return ""
+}
+
+// hintError is a problem with a suggestion for fixing it.
+type hintError struct {
+	Err  error  // required
+	Hint string // required
+}
+
+func (e *hintError) Error() string {
+	return fmt.Sprintf("%v; %v", e.Err, e.Hint)
+}
+
+// Unwrap returns the underlying error.
+func (e *hintError) Unwrap() error {
+	return e.Err
+}
+
+// Position is a position in an input file.
+type Position = goldast.Position
+
+// Stitch combines the files referenced by a summary into one document
+// and returns it.
+//
+// Problems with the inputs are reported as [*InputError]s.
+func Stitch(ctx context.Context, opts Options) ([]byte, error) {
+	res, err := Run(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return res.Output, nil
+}
+
+// Run is like Stitch but reports more information about the result.
+//
+// The Result is non-nil even if Run fails.
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	res := new(Result)
+	if opts.FS == nil {
+		return res, errors.New("no FS specified")
+	}
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+
+	mdParser := goldast.DefaultParser()
+	mdParser.AddOptions(
+		parser.WithASTTransformers(
+			util.Prioritized(&rawhtml.Transformer{}, 100),
+		),
+	)
+	if len(opts.ASTTransformers) > 0 {
+		mdParser.AddOptions(parser.WithASTTransformers(opts.ASTTransformers...))
+	}
+
+	f := goldast.Parse(mdParser, opts.SummaryPath, opts.Summary)
+	summary, err := stitch.ParseSummary(f)
+	if err != nil {
+		return res, &InputError{
+			Code: CodeSummary,
+			Msg:  "error parsing summary",
+			Errs: splitErrors(err),
+		}
+	}
+
+	var stack []string
+	if len(opts.SummaryPath) > 0 {
+		stack = append(stack, opts.SummaryPath)
+	}
+
+	var globIgnore []string
+	if len(opts.OutputPath) > 0 {
+		globIgnore = append(globIgnore, opts.OutputPath)
+	}
+
+	coll, err := (&collector{
+		FS:         opts.FS,
+		Parser:     mdParser,
+		Stack:      stack,
+		GlobIgnore: globIgnore,
+		Slugger:    opts.Slug.slugger(),
+		Profile:    opts.Profile,
+		Vars:       opts.Vars,
+	}).Collect(f.Info, summary)
+	res.Inputs = coll.Inputs
+	if err != nil {
+		return res, &InputError{
+			Code: CodeInput,
+			Msg:  "error reading markdown",
+			Errs: splitErrors(err),
+		}
+	}
+
+	if err := opts.warn(CodeFrontMatter, "found %d problem(s) in front matter", coll.Warnings); err != nil {
+		return res, err
+	}
+
+	// Links must be checked before the transformer rewrites them.
+	linkFS := opts.LinkFS
+	if linkFS == nil {
+		linkFS = opts.FS
+	}
+	linkChecker := linkChecker{FS: linkFS}
+	if err := opts.warn(CodeBrokenLink, "found %d broken link(s)", linkChecker.Check(coll)); err != nil {
+		return res, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+
+	if len(opts.AssetsPath) > 0 {
+		res.assets = &assetBundler{
+			FS:      opts.FS,
+			URLPath: opts.AssetsPath,
+		}
+	}
+
+	inputRelPath := opts.InputRelPath
+	if inputRelPath == "" {
+		inputRelPath = "."
+	}
+
+	(&transformer{
+		Offset:       opts.Offset,
+		InputRelPath: inputRelPath,
+		HeadingIDs:   opts.Format == FormatHTML,
+		SummaryFile:  f,
+		Assets:       res.assets,
+		Number:       opts.Number,
+		Slugger:      opts.Slug.slugger(),
+		Nav:          opts.Nav,
+	}).Transform(coll)
+
+	if res.assets != nil {
+		res.Inputs = append(res.Inputs, res.assets.Sources()...)
+	}
+
+	if len(opts.Hooks) > 0 {
+		if err := runHooks(ctx, opts.Hooks, coll); err != nil {
+			return res, err
+		}
+	}
+
+	// Ensure trailing newline.
+	preface := opts.Preface
+	if len(preface) > 0 && preface[len(preface)-1] != '\n' {
+		preface = append(preface[:len(preface):len(preface)], '\n')
+	}
+
+	var buf bytes.Buffer
+	g := &generator{
+		Preface:  preface,
+		W:        &buf,
+		Renderer: newRenderer(opts.Format),
+		NoTOC:    opts.NoTOC,
+		TOCDepth: opts.TOCDepth,
+		TOCStyle: opts.TOCStyle,
+		Format:   opts.Format,
+	}
+	if err := g.Generate(f.Source, coll); err != nil {
+		return res, err
+	}
+
+	res.Output = buf.Bytes()
+	return res, nil
+}
+
+// splitErrors splits an error joined with errors.Join
+// back into its parts.
+func splitErrors(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
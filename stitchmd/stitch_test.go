@@ -0,0 +1,197 @@
+package stitchmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStitch(t *testing.T) {
+	t.Parallel()
+
+	got, err := Stitch(context.Background(), Options{
+		FS: fstest.MapFS{
+			"intro.md":   {Data: []byte("# Introduction\n\nSee [install](install.md).\n")},
+			"install.md": {Data: []byte("# Install\n\nVersion {{ .Version }}.\n")},
+		},
+		Summary: []byte("- [Intro](intro.md)\n  - [Install](install.md)\n"),
+		Preface: []byte("<!-- generated -->"),
+		Vars:    map[string]string{"Version": "1.2.3"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `<!-- generated -->
+- [Intro](#introduction)
+  - [Install](#install)
+
+# Introduction
+
+See [install](#install).
+
+## Install
+
+Version 1.2.3.
+`, string(got))
+}
+
+func TestStitch_summaryError(t *testing.T) {
+	t.Parallel()
+
+	_, err := Stitch(context.Background(), Options{
+		FS:          fstest.MapFS{},
+		Summary:     []byte("# Foo\n\nnot a list\n"),
+		SummaryPath: "summary.md",
+	})
+	require.Error(t, err)
+
+	var inputErr *InputError
+	require.ErrorAs(t, err, &inputErr)
+	assert.Equal(t, CodeSummary, inputErr.Code)
+	assert.Equal(t, "error parsing summary", inputErr.Msg)
+
+	var posErr *Error
+	require.ErrorAs(t, err, &posErr)
+	assert.Equal(t, Position{File: "summary.md", Line: 3, Column: 1}, posErr.Pos)
+}
+
+func TestRun_inputs(t *testing.T) {
+	t.Parallel()
+
+	res, err := Run(context.Background(), Options{
+		FS: fstest.MapFS{
+			"docs/foo.md":   {Data: []byte("# Foo\n\n![logo](logo.png)\n")},
+			"docs/logo.png": {Data: []byte("logo")},
+		},
+		Summary:    []byte("- [Foo](docs/foo.md)\n"),
+		AssetsPath: "assets",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, string(res.Output), "![logo](assets/logo.png)")
+	assert.Equal(t, []string{"docs/foo.md", "docs/logo.png"}, res.Inputs)
+
+	dir := filepath.Join(t.TempDir(), "assets")
+	require.NoError(t, res.WriteAssets(dir))
+	got, err := os.ReadFile(filepath.Join(dir, "logo.png"))
+	require.NoError(t, err)
+	assert.Equal(t, "logo", string(got))
+}
+
+func TestRun_inputsOnError(t *testing.T) {
+	t.Parallel()
+
+	res, err := Run(context.Background(), Options{
+		FS: fstest.MapFS{
+			"foo.md": {Data: []byte("# Foo")},
+		},
+		Summary: []byte("- [Foo](foo.md)\n- [Bar](bar.md)\n"),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "error reading markdown")
+	assert.Equal(t, []string{"foo.md", "bar.md"}, res.Inputs,
+		"files that couldn't be read should be reported")
+}
+
+func TestRun_includedFileErrorPosition(t *testing.T) {
+	t.Parallel()
+
+	_, err := Run(context.Background(), Options{
+		FS: fstest.MapFS{
+			"foo.md": {Data: []byte("# Foo\n\nHello, {{ .Name }}.\n")},
+		},
+		Summary:     []byte("# Docs\n\n- [Foo](foo.md)\n"),
+		SummaryPath: "summary.md",
+	})
+	require.Error(t, err)
+
+	var inputErr *InputError
+	require.ErrorAs(t, err, &inputErr)
+	assert.Equal(t, CodeInput, inputErr.Code)
+	require.Len(t, inputErr.Errs, 1)
+	assert.EqualError(t, inputErr.Errs[0],
+		`summary.md:3:3:foo.md:3:8:undefined variable "Name"`)
+
+	// The error is reported at the summary item,
+	// but the position in the included file is kept.
+	var itemErr *Error
+	require.ErrorAs(t, inputErr.Errs[0], &itemErr)
+	assert.Equal(t, Position{File: "summary.md", Line: 3, Column: 3}, itemErr.Pos)
+
+	var fileErr *Error
+	require.ErrorAs(t, itemErr.Err, &fileErr)
+	assert.Equal(t, Position{File: "foo.md", Line: 3, Column: 8}, fileErr.Pos)
+}
+
+func TestRun_hint(t *testing.T) {
+	t.Parallel()
+
+	_, err := Run(context.Background(), Options{
+		FS:      os.DirFS(t.TempDir()),
+		Summary: []byte("- [Foo](../foo.md)\n"),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `invalid path "../foo.md"; did you mean to use -unsafe?`)
+	assert.Equal(t, "did you mean to use -unsafe?", Hint(err))
+	assert.Empty(t, Hint(errors.New("great sadness")))
+}
+
+func TestRun_warnings(t *testing.T) {
+	t.Parallel()
+
+	opts := Options{
+		FS: fstest.MapFS{
+			"foo.md": {Data: []byte("# Foo\n\nSee [bar](bar.md).\n")},
+		},
+		Summary: []byte("- [Foo](foo.md)\n"),
+	}
+
+	t.Run("warn", func(t *testing.T) {
+		t.Parallel()
+
+		opts := opts
+		var warnings []*InputError
+		opts.Warn = func(err *InputError) {
+			warnings = append(warnings, err)
+		}
+
+		_, err := Run(context.Background(), opts)
+		require.NoError(t, err)
+		require.Len(t, warnings, 1)
+		assert.Equal(t, CodeBrokenLink, warnings[0].Code)
+		require.Len(t, warnings[0].Errs, 1)
+		assert.EqualError(t, warnings[0].Errs[0], `foo.md:3:6:broken link "bar.md": file not found`)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		t.Parallel()
+
+		opts := opts
+		opts.Strict = true
+
+		_, err := Run(context.Background(), opts)
+		var inputErr *InputError
+		require.ErrorAs(t, err, &inputErr)
+		assert.Equal(t, CodeBrokenLink, inputErr.Code)
+		assert.Equal(t, "found 1 broken link(s)", inputErr.Msg)
+		assert.EqualError(t, err,
+			"found 1 broken link(s)\n"+
+				`foo.md:3:6:broken link "bar.md": file not found`)
+	})
+}
+
+func TestRun_canceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Run(ctx, Options{
+		FS:      fstest.MapFS{},
+		Summary: []byte("- [Foo](foo.md)\n"),
+	})
+	assert.True(t, errors.Is(err, context.Canceled), "got %v", err)
+}
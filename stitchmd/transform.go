@@ -1,11 +1,11 @@
-package main
+package stitchmd
 
 import (
 	"bytes"
 	"fmt"
-	"log"
 	"net/url"
 	"path"
+	"strings"
 
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/text"
@@ -13,6 +13,7 @@ import (
 	"go.abhg.dev/goldmark/toc"
 	"go.abhg.dev/stitchmd/internal/goldast"
 	"go.abhg.dev/stitchmd/internal/goldtext"
+	"go.abhg.dev/stitchmd/internal/header"
 	"go.abhg.dev/stitchmd/internal/must"
 	"go.abhg.dev/stitchmd/internal/rawhtml"
 	"go.abhg.dev/stitchmd/internal/stitch"
@@ -21,8 +22,6 @@ import (
 )
 
 type transformer struct {
-	Log *log.Logger
-
 	// /-separated relative path to the input directory
 	// from wherever the output is going.
 	InputRelPath string
@@ -30,16 +29,61 @@ type transformer struct {
 	// Flat heading offset for all headings.
 	Offset int
 
+	// HeadingIDs specifies that headings should retain
+	// their generated IDs as attributes.
+	// This is needed for output formats that don't generate IDs
+	// automatically, such as HTML.
+	HeadingIDs bool
+
 	SummaryFile *goldast.File
 
+	// Assets, if set, bundles files referenced from Markdown files
+	// that aren't part of the collection.
+	// Links to these files are rewritten to point to the bundled copies.
+	Assets *assetBundler
+
+	// Number specifies that titles of items and their links in the TOC
+	// should be numbered by their position in the summary.
+	Number bool
+
+	// Slugger generates IDs for headings the same way
+	// as the renderer of the Markdown output.
+	// With Number, it's used to find headings that need anchors.
+	Slugger header.Slugger
+
+	// Nav specifies that each file should end with links
+	// to the previous and next files, and the item it's nested under.
+	Nav bool
+
 	// Heading offset for the current section.
 	sectionOffset int
 
 	filesByPath map[string]*markdownFileItem
+
+	// Directory under the input directory
+	// that paths in filesByPath are relative to.
+	dir string
+
+	// Number of footnotes seen so far.
+	// Shared with transformers for embedded summaries
+	// so that footnote numbers are unique across the output.
+	footnotes *int
+
+	// Numbers assigned to items with Number.
+	numbers map[markdownItem]string
+
+	// Number of the embed item whose summary is being transformed,
+	// and the number of top-level items numbered so far.
+	numberPrefix string
+	numberCount  int
 }
 
 func (t *transformer) Transform(coll *markdownCollection) {
 	t.filesByPath = coll.FilesByPath
+	t.dir = coll.Dir
+	if t.footnotes == nil {
+		t.footnotes = new(int)
+	}
 	for _, sec := range coll.Sections {
 		offset := t.Offset
 		if t := sec.Title; t != nil {
@@ -52,6 +96,13 @@ func (t *transformer) Transform(coll *markdownCollection) {
 		}
 		t.sectionOffset = offset
 
+		if t.Number {
+			if t.numbers == nil {
+				t.numbers = make(map[markdownItem]string)
+			}
+			t.assignNumbers(sec.Items, t.numberPrefix, &t.numberCount)
+		}
+
 		err := sec.Items.Walk(func(item markdownItem) error {
 			t.transformItem(item)
 			return nil
@@ -61,6 +112,18 @@ func (t *transformer) Transform(coll *markdownCollection) {
 		// If this fails, something went seriously wrong.
 		must.NotErrorf(err, "Error transforming section")
 	}
+
+	// Files in embedded summaries are linked from this transformer,
+	// so embedded summaries don't need Nav.
+	if t.Nav {
+		t.addNav(coll)
+	}
+
+	// Headings in embedded summaries are anchored from this transformer,
+	// so embedded summaries don't need a Slugger.
+	if t.Number && !t.HeadingIDs && t.Slugger != nil {
+		t.anchorHeadings(coll)
+	}
 }
 
 func (t *transformer) transformItem(item markdownItem) {
@@ -76,17 +139,26 @@ func (t *transformer) transformItem(item markdownItem) {
 	default:
 		panic(fmt.Sprintf("unknown item type: %T", item))
 	}
+
+	if t.Number {
+		t.numberItem(item)
+	}
 }
 
 func (t *transformer) transformEmbed(embed *markdownEmbedItem) {
 	(&transformer{
-		Log:          t.Log,
 		InputRelPath: t.InputRelPath,
+		HeadingIDs:   t.HeadingIDs,
 		Offset:       t.sectionOffset + embed.Item.ItemDepth() + 1,
 		SummaryFile:  embed.SummaryFile,
+		Assets:       t.Assets,
+		Number:       t.Number,
+		footnotes:    t.footnotes,
+		numberPrefix: t.numbers[embed],
 	}).Transform(&markdownCollection{
 		Sections:    []*markdownSection{embed.Section},
 		FilesByPath: embed.FilesByPath,
+		Dir:         embed.Dir,
 	})
 
 	embed.src = t.transformHeading(embed.src, embed.Item, embed.Heading)
@@ -98,6 +170,7 @@ func (t *transformer) transformEmbed(embed *markdownEmbedItem) {
 	link := ast.NewLink()
 	link.Destination = []byte("#" + embed.Heading.ID)
 	parent.ReplaceChild(parent, item, link)
+	embed.link = link
 	for c := item.FirstChild(); c != nil; c = c.NextSibling() {
 		link.AppendChild(link, c)
 	}
@@ -172,7 +245,13 @@ func (t *transformer) transformFile(f *markdownFileItem) {
 	}
 	f.File.Source = src
 
-	t.transformLink(".", f, f.Item.AST)
+	if f.Section != "" {
+		// The file may also be included in full elsewhere,
+		// so don't resolve the link through it.
+		f.Item.AST.Destination = []byte("#" + f.Title.ID)
+	} else {
+		t.transformLink(".", f, f.Item.AST)
+	}
 
 	fromPath := path.Dir(f.Path)
 	for _, l := range f.Links {
@@ -183,6 +262,8 @@ func (t *transformer) transformFile(f *markdownFileItem) {
 		t.transformImage(fromPath, f, i)
 	}
 
+	t.renumberFootnotes(f.File.AST)
+
 	src = f.File.Source
 	for _, pair := range f.HTMLPairs {
 		src = t.transformHTMLPair(src, fromPath, f, pair)
@@ -195,9 +276,16 @@ func (t *transformer) transformFile(f *markdownFileItem) {
 	}
 	f.File.Source = src
 
+	// If the file asked to be left out of the summary,
+	// remove it, keeping any items nested under it.
+	// There's no place to absorb headings into in that case.
+	if f.NoTOC {
+		removeTOCItem(f.Item.AST, true /* keepChildren */)
+	}
+
 	// If the file requested absorbtion of headings, add them to the summary
 	// as TOC items.
-	if f.Absorb && len(f.Headings) > 0 {
+	if f.Absorb && !f.NoTOC && len(f.Headings) > 0 {
 		var parentListItem *ast.ListItem
 		for item := f.Item.AST.Parent(); item != nil; item = item.Parent() {
 			if li, ok := item.(*ast.ListItem); ok {
@@ -206,7 +294,7 @@ func (t *transformer) transformFile(f *markdownFileItem) {
 			}
 		}
 		if parentListItem == nil {
-			t.Log.Panicf("could not find parent list item for %q", f.Path)
+			panic(fmt.Sprintf("could not find parent list item for %q", f.Path))
 		}
 		parentList := parentListItem.Parent().(*ast.List)
 
@@ -238,8 +326,11 @@ func (t *transformer) transformFile(f *markdownFileItem) {
 			link.Destination = append([]byte("#"), item.ID...)
 			link.AppendChild(link, title)
 
+			text := ast.NewTextBlock()
+			text.AppendChild(text, link)
+
 			listItem := ast.NewListItem(0)
-			listItem.AppendChild(listItem, link)
+			listItem.AppendChild(listItem, text)
 
 			if items := renderItems(item.Items); items != nil {
 				listItem.AppendChild(listItem, items)
@@ -373,6 +464,9 @@ func (t *transformer) transformHTMLNode(fromPath string, f *markdownFileItem, n
 func (t *transformer) transformHeading(src []byte, item stitch.Item, h *markdownHeading) []byte {
 	// GitHub doesn't support Heading attribute syntax.
 	h.AST.RemoveAttributes()
+	if t.HeadingIDs {
+		h.AST.SetAttributeString("id", []byte(h.ID))
+	}
 
 	h.Lvl += item.ItemDepth() + t.sectionOffset
 	if h.Lvl < 1 {
@@ -434,8 +528,16 @@ func (t *transformer) transformURL(fromPath string, f *markdownFileItem, toURL s
 			// This is a relative path that does not point to a Markdown
 			// file in the collection.
 			// It may be a link to a file in the input directory.
+			src := path.Join(t.dir, dst)
+			if t.Assets != nil && !isMarkdownPath(src) {
+				if assetPath, ok := t.Assets.Add(src); ok {
+					u.Path = assetPath
+					return u.String()
+				}
+			}
+
 			// Update the path and leave everything else as-is.
-			u.Path = path.Join(t.InputRelPath, dst)
+			u.Path = path.Join(t.InputRelPath, src)
 			return u.String()
 		}
 		u.Path = ""
@@ -447,9 +549,25 @@ func (t *transformer) transformURL(fromPath string, f *markdownFileItem, toURL s
 		// use the new ID of that header.
 		if h, ok := to.HeadingsByOldID[u.Fragment]; ok {
 			u.Fragment = h.ID
+		} else if to.Section != "" {
+			// The heading is in a part of the file
+			// that wasn't included.
+			// Link to the original file instead.
+			u.Path = path.Join(t.InputRelPath, t.dir, to.Path)
 		}
 	} else {
 		u.Fragment = to.Title.ID
 	}
 	return u.String()
 }
+
+// isMarkdownPath reports whether the given path is a Markdown file
+// based on its extension.
+func isMarkdownPath(p string) bool {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".md", ".markdown":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,102 @@
+package stitchmd
+
+import (
+	"regexp"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+	"go.abhg.dev/stitchmd/internal/goldast"
+)
+
+// Text in included Markdown files may reference variables
+// with the syntax:
+//
+//	{{ .Name }}
+//
+// References are replaced with the values of the variables
+// before headings are inspected,
+// so variables may be used in titles.
+//
+// Variables are defined with Options.Vars.
+
+var _varRef = regexp.MustCompile(`\{\{\s*\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// expandVars replaces references to variables in text in the given file
+// with their values.
+// References to undefined variables are reported as errors.
+//
+// Code spans and code blocks are left alone unless code is true.
+func (c *collector) expandVars(f *goldast.File, code bool) error {
+	errs := goldast.NewInlineErrorList(f.Info)
+
+	// expand reports the given segment with variables replaced,
+	// appending the new text to the file's source if needed.
+	expand := func(seg text.Segment) text.Segment {
+		value := seg.Value(f.Source)
+		matches := _varRef.FindAllSubmatchIndex(value, -1)
+		if len(matches) == 0 {
+			return seg
+		}
+
+		var (
+			out  []byte
+			last int
+		)
+		for _, m := range matches {
+			out = append(out, value[last:m[0]]...)
+			last = m[1]
+
+			name := string(value[m[2]:m[3]])
+			v, ok := c.Vars[name]
+			if !ok {
+				// Point the error at the reference itself.
+				ref := ast.NewTextSegment(text.NewSegment(seg.Start+m[0], seg.Start+m[1]))
+				errs.Pushf(ref, "undefined variable %q", name)
+				v = string(value[m[0]:m[1]])
+			}
+			out = append(out, v...)
+		}
+		out = append(out, value[last:]...)
+
+		start := len(f.Source)
+		f.Source = append(f.Source, out...)
+		newSeg := text.NewSegment(start, len(f.Source))
+		newSeg.Padding = seg.Padding
+		return newSeg
+	}
+
+	_ = ast.Walk(f.AST, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch n := n.(type) {
+		case *ast.CodeSpan:
+			if !code {
+				return ast.WalkSkipChildren, nil
+			}
+
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			if code {
+				lines := n.Lines()
+				for i := 0; i < lines.Len(); i++ {
+					lines.Set(i, expand(lines.At(i)))
+				}
+			}
+			return ast.WalkSkipChildren, nil
+
+		case *ast.Text:
+			n.Segment = expand(n.Segment)
+
+		default:
+			// References may be split across adjacent text nodes
+			// around characters with special meaning, like '_'.
+			if n.Type() == ast.TypeBlock && n.HasChildren() {
+				goldast.CombineAdjacentTexts(n, f.Source)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return errs.Err()
+}
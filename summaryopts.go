@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark/parser"
+	"go.abhg.dev/goldmark/frontmatter"
+	"go.abhg.dev/stitchmd/internal/goldast"
+	"go.abhg.dev/stitchmd/stitchmd"
+)
+
+// summaryOptions are options for a summary file
+// specified in its front matter.
+// They correspond to the command line flags with the same names.
+//
+//	---
+//	output: ../README.md
+//	preface: preface.txt
+//	offset: 1
+//	---
+//
+// Fields are pointers to distinguish between unset and zero values.
+// Paths are relative to the directory of the summary file.
+type summaryOptions struct {
+	Output   *string             `yaml:"output" toml:"output"`
+	DepFile  *string             `yaml:"depfile" toml:"depfile"`
+	Assets   *string             `yaml:"assets" toml:"assets"`
+	Preface  *string             `yaml:"preface" toml:"preface"`
+	Offset   *int                `yaml:"offset" toml:"offset"`
+	NoTOC    *bool               `yaml:"no-toc" toml:"no-toc"`
+	TOCDepth *int                `yaml:"toc-depth" toml:"toc-depth"`
+	TOCStyle *stitchmd.TOCStyle  `yaml:"toc-style" toml:"toc-style"`
+	Number   *bool               `yaml:"number" toml:"number"`
+	Nav      *bool               `yaml:"nav" toml:"nav"`
+	Format   *stitchmd.Format    `yaml:"format" toml:"format"`
+	Slug     *stitchmd.SlugStyle `yaml:"slug" toml:"slug"`
+	Profile  *string             `yaml:"profile" toml:"profile"`
+	VarFile  *string             `yaml:"var-file" toml:"var-file"`
+
+	// Variables that may be referenced from included files.
+	// Variables set with -var-file and -var take precedence over these.
+	Vars map[string]string `yaml:"vars" toml:"vars"`
+}
+
+// _summaryOptionKeys is the set of keys recognized
+// in the front matter of a summary file.
+var _summaryOptionKeys = func() map[string]struct{} {
+	keys := make(map[string]struct{})
+	typ := reflect.TypeOf(summaryOptions{})
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("yaml"), ",")
+		keys[name] = struct{}{}
+	}
+	return keys
+}()
+
+// loadSummaryOptions reads options from the front matter
+// of the summary file at the given path.
+//
+// It returns nil if the file doesn't exist or doesn't have front matter.
+// Unknown keys are reported as warnings.
+func loadSummaryOptions(path string) (*summaryOptions, []error, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// Reported when the summary is read.
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	ctx := parser.NewContext()
+	f := goldast.Parse(goldast.DefaultParser(), path, src, parser.WithContext(ctx))
+	data := frontmatter.Get(ctx)
+	if data == nil {
+		return nil, nil, nil
+	}
+
+	var opts summaryOptions
+	if err := data.Decode(&opts); err != nil {
+		return nil, nil, fmt.Errorf("%v: bad frontmatter: %v", path, err)
+	}
+
+	for name := range opts.Vars {
+		if !_varName.MatchString(name) {
+			return nil, nil, fmt.Errorf("%v: bad frontmatter: invalid variable name %q", path, name)
+		}
+	}
+	if opts.TOCDepth != nil && *opts.TOCDepth < 0 {
+		return nil, nil, fmt.Errorf("%v: bad frontmatter: toc-depth must not be negative", path)
+	}
+
+	var raw map[string]any
+	if err := data.Decode(&raw); err != nil {
+		return nil, nil, fmt.Errorf("%v: bad frontmatter: %v", path, err)
+	}
+	unknown := make([]string, 0, len(raw))
+	for key := range raw {
+		if _, ok := _summaryOptionKeys[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+
+	var warnings []error
+	for _, key := range unknown {
+		pos := f.Info.Position(goldast.FrontMatterKeyOffset(src, key))
+		warnings = append(warnings, &goldast.Error{
+			Pos:    pos,
+			Err:    fmt.Errorf("unknown front matter key %q", key),
+			Source: f.Info.Line(pos.Line),
+		})
+	}
+
+	return &opts, warnings, nil
+}
+
+// apply fills parameters from these options,
+// skipping those for which isSet reports true.
+// isSet is called with the names of the corresponding flags.
+//
+// Relative paths are resolved against dir.
+func (o *summaryOptions) apply(opts *params, dir string, isSet func(name string) bool) {
+	setString := func(name string, dst *string, src *string) {
+		if src != nil && !isSet(name) {
+			*dst = resolvePath(dir, *src)
+		}
+	}
+	setString("o", &opts.Output, o.Output)
+	setString("depfile", &opts.DepFile, o.DepFile)
+	setString("assets", &opts.Assets, o.Assets)
+	setString("preface", &opts.Preface, o.Preface)
+	setString("var-file", &opts.VarFile, o.VarFile)
+	if opts.Output == "-" {
+		opts.Output = ""
+	}
+
+	if o.Offset != nil && !isSet("offset") {
+		opts.Offset = *o.Offset
+	}
+	if o.NoTOC != nil && !isSet("no-toc") {
+		opts.NoTOC = *o.NoTOC
+	}
+	if o.TOCDepth != nil && !isSet("toc-depth") {
+		opts.TOCDepth = *o.TOCDepth
+	}
+	if o.TOCStyle != nil && !isSet("toc-style") {
+		opts.TOCStyle = *o.TOCStyle
+	}
+	if o.Number != nil && !isSet("number") {
+		opts.Number = *o.Number
+	}
+	if o.Nav != nil && !isSet("nav") {
+		opts.Nav = *o.Nav
+	}
+	if o.Format != nil && !isSet("format") {
+		opts.Format = *o.Format
+	}
+	if o.Slug != nil && !isSet("slug") {
+		opts.Slug = *o.Slug
+	}
+	if o.Profile != nil && !isSet("profile") {
+		opts.Profile = *o.Profile
+	}
+	if len(o.Vars) > 0 {
+		opts.SummaryVars = maps.Clone(o.Vars)
+	}
+}
+
+// applySummaryOptions fills parameters that weren't set explicitly
+// from the front matter of the input summary file, if any.
+// isSet reports whether the flag with the given name was set explicitly.
+//
+// Unknown keys in the front matter are returned as warnings.
+func applySummaryOptions(opts *params, isSet func(name string) bool) (warnings []error, err error) {
+	if opts.Input == "" {
+		return nil, nil // stdin
+	}
+
+	summaryOpts, warnings, err := loadSummaryOptions(opts.Input)
+	if err != nil || summaryOpts == nil {
+		return warnings, err
+	}
+	summaryOpts.apply(opts, filepath.Dir(opts.Input), isSet)
+	return warnings, nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/stitchmd/stitchmd"
+)
+
+func TestCLIParser_summaryOptions(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	summary := filepath.Join(dir, "doc", "SUMMARY.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(summary), 0o755))
+	require.NoError(t, os.WriteFile(summary, []byte(
+		"---\n"+
+			"output: ../README.md\n"+
+			"preface: preface.txt\n"+
+			"depfile: /tmp/README.d\n"+
+			"offset: 1\n"+
+			"no-toc: true\n"+
+			"format: html\n"+
+			"slug: gitlab\n"+
+			"vars:\n"+
+			"  Version: 1.0\n"+
+			"  Product: Foo\n"+
+			"---\n\n"+
+			"- [foo](foo.md)\n",
+	), 0o644))
+
+	tests := []struct {
+		desc string
+		args []string
+		want params
+	}{
+		{
+			desc: "from summary",
+			want: params{
+				Input:   summary,
+				Output:  filepath.Join(dir, "README.md"),
+				Preface: filepath.Join(dir, "doc", "preface.txt"),
+				DepFile: "/tmp/README.d",
+				Offset:  1,
+				NoTOC:   true,
+				Format:  stitchmd.FormatHTML,
+				Slug:    stitchmd.SlugGitLab,
+
+				SummaryVars: varMap{"Version": "1.0", "Product": "Foo"},
+			},
+		},
+		{
+			desc: "flags win",
+			args: []string{
+				"-o", "out.md",
+				"-M", "out.d",
+				"-offset", "0",
+				"-no-toc=false",
+				"-format", "markdown",
+				"-var", "Version=2.0",
+			},
+			want: params{
+				Input:   summary,
+				Output:  "out.md",
+				Preface: filepath.Join(dir, "doc", "preface.txt"),
+				DepFile: "out.d",
+				Slug:    stitchmd.SlugGitLab,
+				Vars:    varMap{"Version": "2.0"},
+
+				SummaryVars: varMap{"Version": "1.0", "Product": "Foo"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var stderr bytes.Buffer
+			got, res := (&cliParser{
+				Stdout: io.Discard,
+				Stderr: &stderr,
+			}).Parse(append(tt.args, summary))
+			require.Equal(t, cliParseSuccess, res, "stderr: %s", stderr.String())
+			assert.Equal(t, &tt.want, got)
+			assert.Empty(t, stderr.String())
+		})
+	}
+}
+
+func TestCLIParser_summaryOptionsProblems(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc       string
+		give       string
+		args       []string
+		wantRes    cliParseResult
+		wantStderr string
+	}{
+		{
+			desc:    "unknown key",
+			give:    "---\nouptut: foo.md\n---\n\n- [foo](foo.md)\n",
+			wantRes: cliParseSuccess,
+			wantStderr: `warning: SUMMARY.md:2:1:unknown front matter key "ouptut"` + "\n" +
+				"  2 | ouptut: foo.md\n" +
+				"    | ^\n",
+		},
+		{
+			desc:    "unknown key/strict",
+			give:    "---\nouptut: foo.md\n---\n\n- [foo](foo.md)\n",
+			args:    []string{"-strict"},
+			wantRes: cliParseError,
+			wantStderr: `SUMMARY.md:2:1:unknown front matter key "ouptut"` + "\n" +
+				"  2 | ouptut: foo.md\n" +
+				"    | ^\n" +
+				"found 1 problem(s) in front matter\n",
+		},
+		{
+			desc:       "bad value",
+			give:       "---\nformat: pdf\n---\n\n- [foo](foo.md)\n",
+			wantRes:    cliParseError,
+			wantStderr: "must be one of 'markdown', 'html'",
+		},
+		{
+			desc:       "bad variable name",
+			give:       "---\nvars:\n  foo-bar: baz\n---\n\n- [foo](foo.md)\n",
+			wantRes:    cliParseError,
+			wantStderr: `invalid variable name "foo-bar"`,
+		},
+		{
+			desc:       "negative toc-depth",
+			give:       "---\ntoc-depth: -1\n---\n\n- [foo](foo.md)\n",
+			wantRes:    cliParseError,
+			wantStderr: "toc-depth must not be negative",
+		},
+		{
+			desc:       "validated after merge",
+			give:       "---\nassets: assets\n---\n\n- [foo](foo.md)\n",
+			wantRes:    cliParseError,
+			wantStderr: "cannot use -assets without -o",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			summary := filepath.Join(dir, "SUMMARY.md")
+			require.NoError(t, os.WriteFile(summary, []byte(tt.give), 0o644))
+
+			var stderr bytes.Buffer
+			_, res := (&cliParser{
+				Stdout: io.Discard,
+				Stderr: &stderr,
+			}).Parse(append(tt.args, summary))
+			assert.Equal(t, tt.wantRes, res)
+
+			// Positions use the path that was passed in.
+			got := bytes.ReplaceAll(stderr.Bytes(), []byte(summary), []byte("SUMMARY.md"))
+			assert.Contains(t, string(got), tt.wantStderr)
+		})
+	}
+}
+
+func TestMain_configSummaryOptions(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile := func(name, contents string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	}
+
+	writeFile("stitchmd.yaml", `
+jobs:
+  - input: doc/SUMMARY.md
+  - input: doc/SUMMARY.md
+    output: OTHER.md
+    offset: 2
+  - input: doc/SUMMARY.md
+    output: ZERO.md
+    offset: 0
+    no-toc: false
+`)
+	writeFile("doc/SUMMARY.md", "---\noutput: ../README.md\noffset: 1\nno-toc: true\n---\n\n- [Intro](intro.md)\n")
+	writeFile("doc/intro.md", "# Intro\n")
+
+	var stderr bytes.Buffer
+	exitCode := (&mainCmd{
+		Stdin:  bytes.NewReader(nil),
+		Stdout: io.Discard,
+		Stderr: &stderr,
+		Getwd: func() (string, error) {
+			return dir, nil
+		},
+		Getenv: nopGetenv,
+	}).Run([]string{"-config", filepath.Join(dir, "stitchmd.yaml")})
+	require.Equal(t, _exitOK, exitCode, "stderr: %s", stderr.String())
+
+	readme, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "## Intro\n", string(readme))
+
+	other, err := os.ReadFile(filepath.Join(dir, "OTHER.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "### Intro\n", string(other))
+
+	// Fields set to their zero values in the job
+	// still take precedence over the summary.
+	zero, err := os.ReadFile(filepath.Join(dir, "ZERO.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "- [Intro](#intro)\n\n# Intro\n", string(zero))
+}
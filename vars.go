@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Variables are defined with -var, -var-file,
+// or the "vars" field of the summary's front matter.
+// See stitchmd.Options.Vars for how they're used.
+
+// _varName matches valid variable names.
+var _varName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// varMap is a set of variables specified with repeated -var flags.
+type varMap map[string]string
+
+var _ flag.Getter = (*varMap)(nil)
+
+func (m varMap) String() string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + m[k]
+	}
+	return strings.Join(pairs, " ")
+}
+
+func (m varMap) Get() interface{} {
+	return m
+}
+
+func (m *varMap) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return errors.New("must be in the form NAME=VALUE")
+	}
+	if !_varName.MatchString(name) {
+		return fmt.Errorf("invalid variable name %q", name)
+	}
+
+	if *m == nil {
+		*m = make(varMap)
+	}
+	(*m)[name] = value
+	return nil
+}
+
+// loadVarFile reads variables from a YAML file
+// with a variable name and value on each line.
+//
+//	Version: 1.2.3
+//	Product: Foo
+func loadVarFile(path string) (map[string]string, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var vars map[string]string
+	if err := yaml.Unmarshal(src, &vars); err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	for name := range vars {
+		if !_varName.MatchString(name) {
+			return nil, fmt.Errorf("%v: invalid variable name %q", path, name)
+		}
+	}
+	return vars, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVarMap(t *testing.T) {
+	t.Parallel()
+
+	var m varMap
+	assert.Equal(t, "", m.String())
+
+	require.NoError(t, m.Set("b=2"))
+	require.NoError(t, m.Set("a=x=y"))
+	require.NoError(t, m.Set("empty="))
+	assert.Equal(t, varMap{"a": "x=y", "b": "2", "empty": ""}, m.Get())
+	assert.Equal(t, "a=x=y b=2 empty=", m.String())
+
+	assert.ErrorContains(t, m.Set("a"), "must be in the form NAME=VALUE")
+	assert.ErrorContains(t, m.Set("1a=b"), `invalid variable name "1a"`)
+}
+
+func TestLoadVarFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+		return path
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := loadVarFile(writeFile("valid.yaml", "Version: 1.2.3\nProduct: Foo\n"))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"Version": "1.2.3", "Product": "Foo"}, got)
+	})
+
+	t.Run("bad name", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := loadVarFile(writeFile("bad_name.yaml", "foo bar: baz\n"))
+		assert.ErrorContains(t, err, `invalid variable name "foo bar"`)
+	})
+
+	t.Run("not a map", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := loadVarFile(writeFile("list.yaml", "- foo\n"))
+		assert.Error(t, err)
+	})
+
+	t.Run("does not exist", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := loadVarFile(filepath.Join(dir, "does-not-exist.yaml"))
+		assertNoSuchFileError(t, err.Error())
+	})
+}
+
+func TestMain_varFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile := func(name, contents string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	}
+
+	writeFile("SUMMARY.md", "---\nvars:\n  Name: summary\n  Edition: Community\n---\n\n- [foo](foo.md)\n")
+	writeFile("foo.md", "# Foo\n\n{{ .Product }} {{ .Name }} {{ .Edition }} {{ .Version }}\n")
+	writeFile("vars.yaml", "Product: Foo\nName: file\nEdition: Enterprise\nVersion: 1.0\n")
+
+	var stdout, stderr bytes.Buffer
+	exitCode := (&mainCmd{
+		Stdin:  bytes.NewReader(nil),
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Getwd: func() (string, error) {
+			return dir, nil
+		},
+		Getenv: nopGetenv,
+	}).Run([]string{
+		"-var-file", filepath.Join(dir, "vars.yaml"),
+		"-var", "Version=2.0",
+		filepath.Join(dir, "SUMMARY.md"),
+	})
+	require.Equal(t, 0, exitCode, "stderr: %s", stderr.String())
+
+	// -var beats the variable file, which beats the front matter.
+	assert.Contains(t, stdout.String(), "Foo file Enterprise 2.0\n")
+}
+
+func TestMain_varFileDoesNotExist(t *testing.T) {
+	t.Parallel()
+
+	var stderr bytes.Buffer
+	exitCode := (&mainCmd{
+		Stdin:  bytes.NewReader([]byte("- [foo](foo.md)")),
+		Stdout: io.Discard,
+		Stderr: &stderr,
+		Getwd:  os.Getwd,
+		Getenv: nopGetenv,
+	}).Run([]string{"-var-file", "does-not-exist.yaml", "-"})
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, stderr.String(), "-var-file: open does-not-exist.yaml:")
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// pollWatcher watches a set of files for changes
+// by periodically checking their metadata.
+//
+// Polling is used instead of filesystem notifications
+// so that this works on any filesystem.
+type pollWatcher struct {
+	// Interval between successive checks of the files.
+	Interval time.Duration // required
+
+	// Duration for which the files must remain unchanged
+	// after a change before the change is reported.
+	// This coalesces bursts of changes (e.g. an editor saving several
+	// files) into a single rebuild.
+	Debounce time.Duration
+
+	// Stat reports information about a file.
+	Stat func(string) (fs.FileInfo, error) // required (os.Stat)
+}
+
+// fileState is a snapshot of a file's metadata.
+type fileState struct {
+	Exists  bool
+	Size    int64
+	ModTime time.Time
+}
+
+func (s fileState) equal(o fileState) bool {
+	return s.Exists == o.Exists &&
+		s.Size == o.Size &&
+		s.ModTime.Equal(o.ModTime)
+}
+
+func (w *pollWatcher) snapshot(paths []string) map[string]fileState {
+	states := make(map[string]fileState, len(paths))
+	for _, p := range paths {
+		// Files that can't be stat-ed are treated as missing.
+		// They may be created later.
+		var st fileState
+		if info, err := w.Stat(p); err == nil {
+			st = fileState{
+				Exists:  true,
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+			}
+		}
+		states[p] = st
+	}
+	return states
+}
+
+func statesEqual(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for p, sa := range a {
+		sb, ok := b[p]
+		if !ok || !sa.equal(sb) {
+			return false
+		}
+	}
+	return true
+}
+
+// Wait blocks until one of the given files changes,
+// and then remains unchanged for the debounce duration.
+//
+// It returns the context's error if the context is cancelled first.
+func (w *pollWatcher) Wait(ctx context.Context, paths []string) error {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	last := w.snapshot(paths)
+	var (
+		changed     bool
+		lastChanged time.Time
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case now := <-ticker.C:
+			if cur := w.snapshot(paths); !statesEqual(last, cur) {
+				changed = true
+				lastChanged = now
+				last = cur
+				continue
+			}
+
+			if changed && now.Sub(lastChanged) >= w.Debounce {
+				return nil
+			}
+		}
+	}
+}
+
+// watchUntilInterrupted watches and regenerates the outputs
+// for the given jobs until the program is interrupted.
+func (cmd *mainCmd) watchUntilInterrupted(jobs []*params) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return cmd.watch(ctx, jobs, newPollWatcher())
+}
+
+// watch regenerates the outputs of the given jobs
+// every time one of their inputs changes.
+// It runs until the context is cancelled.
+//
+// Errors encountered while generating the outputs are reported
+// but do not stop the watcher.
+func (cmd *mainCmd) watch(ctx context.Context, jobs []*params, w *pollWatcher) error {
+	log := log.New(cmd.Stderr, "", 0)
+	for {
+		// The list of inputs is re-computed on every run
+		// so that changes to the summary are picked up.
+		var watchList []string
+		for _, opts := range jobs {
+			inputs, err := cmd.stitch(opts)
+			if err != nil {
+				log.Println("stitchmd:", err)
+			} else {
+				log.Printf("wrote %v", opts.Output)
+			}
+
+			watchList = append(watchList, opts.Input)
+			if len(opts.Preface) > 0 {
+				watchList = append(watchList, opts.Preface)
+			}
+			if len(opts.VarFile) > 0 {
+				watchList = append(watchList, opts.VarFile)
+			}
+			watchList = append(watchList, inputs...)
+		}
+
+		if err := w.Wait(ctx, watchList); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return fmt.Errorf("watch: %w", err)
+		}
+	}
+}
+
+func newPollWatcher() *pollWatcher {
+	return &pollWatcher{
+		Interval: 250 * time.Millisecond,
+		Debounce: 100 * time.Millisecond,
+		Stat:     os.Stat,
+	}
+}
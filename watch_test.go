@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollWatcher(t *testing.T) {
+	t.Parallel()
+
+	// fakeFS is a thread-safe fstest.MapFS.
+	type fakeFS struct {
+		mu    sync.Mutex
+		files fstest.MapFS
+	}
+	newWatcher := func(fsys *fakeFS) *pollWatcher {
+		return &pollWatcher{
+			Interval: time.Millisecond,
+			Debounce: 5 * time.Millisecond,
+			Stat: func(name string) (fs.FileInfo, error) {
+				fsys.mu.Lock()
+				defer fsys.mu.Unlock()
+				return fs.Stat(fsys.files, name)
+			},
+		}
+	}
+
+	t.Run("modified", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := &fakeFS{files: fstest.MapFS{
+			"foo.md": {Data: []byte("foo")},
+		}}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- newWatcher(fsys).Wait(context.Background(), []string{"foo.md"})
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		fsys.mu.Lock()
+		fsys.files["foo.md"] = &fstest.MapFile{Data: []byte("foo bar")}
+		fsys.mu.Unlock()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("change not detected")
+		}
+	})
+
+	t.Run("created", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := &fakeFS{files: fstest.MapFS{}}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- newWatcher(fsys).Wait(context.Background(), []string{"foo.md"})
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		fsys.mu.Lock()
+		fsys.files["foo.md"] = &fstest.MapFile{}
+		fsys.mu.Unlock()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("change not detected")
+		}
+	})
+
+	t.Run("unrelated file", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := &fakeFS{files: fstest.MapFS{
+			"foo.md": {Data: []byte("foo")},
+		}}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			done <- newWatcher(fsys).Wait(ctx, []string{"foo.md"})
+		}()
+
+		fsys.mu.Lock()
+		fsys.files["bar.md"] = &fstest.MapFile{Data: []byte("bar")}
+		fsys.mu.Unlock()
+
+		assert.ErrorIs(t, <-done, context.DeadlineExceeded)
+	})
+}
+
+func TestMain_watch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile := func(name, contents string) {
+		require.NoError(t,
+			os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644))
+	}
+	output := filepath.Join(dir, "out.md")
+	readOutput := func() string {
+		bs, _ := os.ReadFile(output)
+		return string(bs)
+	}
+
+	writeFile("summary.md", "- [foo](foo.md)\n")
+	writeFile("foo.md", "# Foo\n\nfoo\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var stderr lockedBuffer
+	cmd := &mainCmd{
+		Stdin:  bytes.NewReader(nil),
+		Stdout: new(bytes.Buffer),
+		Stderr: &stderr,
+		Getwd: func() (string, error) {
+			return dir, nil
+		},
+		Getenv: nopGetenv,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.watch(ctx, []*params{{
+			Input:  filepath.Join(dir, "summary.md"),
+			Output: output,
+		}}, &pollWatcher{
+			Interval: 5 * time.Millisecond,
+			Debounce: 10 * time.Millisecond,
+			Stat:     os.Stat,
+		})
+	}()
+
+	require.Eventually(t, func() bool {
+		return readOutput() == "- [foo](#foo)\n\n# Foo\n\nfoo\n"
+	}, 5*time.Second, 5*time.Millisecond, "initial build")
+
+	// Change to a linked file.
+	writeFile("foo.md", "# Foo\n\nfoo changed\n")
+	require.Eventually(t, func() bool {
+		return readOutput() == "- [foo](#foo)\n\n# Foo\n\nfoo changed\n"
+	}, 5*time.Second, 5*time.Millisecond, "linked file changed")
+
+	// Summary adds a new file that does not exist yet.
+	// The build will fail, but the watcher should pick it up
+	// once it's created.
+	writeFile("summary.md", "- [foo](foo.md)\n- [bar](bar.md)\n")
+	require.Eventually(t, func() bool {
+		return strings.Contains(stderr.String(), "error reading markdown")
+	}, 5*time.Second, 5*time.Millisecond, "missing file reported")
+
+	writeFile("bar.md", "# Bar\n\nbar\n")
+	require.Eventually(t, func() bool {
+		return readOutput() == "- [foo](#foo)\n- [bar](#bar)\n\n# Foo\n\nfoo changed\n\n# Bar\n\nbar\n"
+	}, 5*time.Second, 5*time.Millisecond, "new file created")
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not stop")
+	}
+}
+
+// lockedBuffer is a bytes.Buffer that is safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}